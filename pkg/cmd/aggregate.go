@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// getNodesAllocatable returns total allocatable cpu (millicores) and memory (bytes) of nodes
+func getNodesAllocatable(nodes []v1.Node) (int64, int64) {
+
+	var cpu, mem int64
+	for _, node := range nodes {
+		cpu += node.Status.Allocatable.Cpu().MilliValue()
+		mem += node.Status.Allocatable.Memory().Value()
+	}
+
+	return cpu, mem
+}
+
+// addAggregateResource adds raw values of a container resource to sum
+func addAggregateResource(sum *types.AggregateResource, r types.ContainerResource) {
+	sum.Used += r.Used
+	sum.Requested += r.Requested
+	sum.Limited += r.Limited
+}
+
+// getPodResource returns effective cpu and memory of a pod
+// Requests and limits are computed from the pod spec as the scheduler does (init containers and pod overhead).
+// Used is a sum of all containers because metrics are reported per container.
+func getPodResource(pod v1.Pod, metrics util.ContainerMetricsIndex) (types.ContainerResource, types.ContainerResource) {
+
+	cpu, mem := types.ContainerResource{}, types.ContainerResource{}
+	cpu.Requested, cpu.Limited = util.GetPodRequestAndLimit(pod, v1.ResourceCPU)
+	mem.Requested, mem.Limited = util.GetPodRequestAndLimit(pod, v1.ResourceMemory)
+
+	containers := []v1.Container{}
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+
+	for _, c := range containers {
+		cpuUsed, memUsed := metrics.Get(pod.ObjectMeta.Namespace, pod.ObjectMeta.Name, c.Name)
+		cpu.Used += cpuUsed
+		mem.Used += memUsed
+	}
+
+	return cpu, mem
+}
+
+// setAggregateResourcePercentage computes percentages from raw values
+func setAggregateResourcePercentage(r *types.AggregateResource, allocatable int64) {
+	r.UsedPercent = util.GetPercentage(r.Used, allocatable)
+	r.RequestedPercent = util.GetPercentage(r.Requested, allocatable)
+	r.LimitedPercent = util.GetPercentage(r.Limited, allocatable)
+	r.UsedRequestedPercent = util.GetPercentage(r.Used, r.Requested)
+}
+
+// aggregateTableColumns returns cpu and memory columns of an aggregated row
+func (o *FreeOptions) aggregateTableColumns(cpu, mem types.AggregateResource) []string {
+
+	row := []string{}
+
+	// cpu
+	if !o.noMetrics {
+		row = append(row, o.toMilliUnitOrDash(cpu.Used)) // cpu used (from metrics)
+	}
+	row = append(
+		row,
+		o.toMilliUnitOrDash(cpu.Requested), // cpu requested
+		o.toMilliUnitOrDash(cpu.Limited),   // cpu limited
+	)
+	if !o.noMetrics {
+		row = append(row, o.toColorPercent(cpu.UsedPercent)) // cpu used %
+	}
+	row = append(
+		row,
+		o.toColorPercent(cpu.RequestedPercent), // cpu requested %
+		o.toColorPercent(cpu.LimitedPercent),   // cpu limited %
+	)
+	if !o.noMetrics {
+		row = append(row, strconv.FormatInt(cpu.UsedRequestedPercent, 10)+"%") // cpu used / requested
+	}
+
+	// mem
+	if !o.noMetrics {
+		row = append(row, o.toUnitOrDash(mem.Used)) // mem used (from metrics)
+	}
+	row = append(
+		row,
+		o.toUnitOrDash(mem.Requested), // mem requested
+		o.toUnitOrDash(mem.Limited),   // mem limited
+	)
+	if !o.noMetrics {
+		row = append(row, o.toColorPercent(mem.UsedPercent)) // mem used %
+	}
+	row = append(
+		row,
+		o.toColorPercent(mem.RequestedPercent), // mem requested %
+		o.toColorPercent(mem.LimitedPercent),   // mem limited %
+	)
+	if !o.noMetrics {
+		row = append(row, strconv.FormatInt(mem.UsedRequestedPercent, 10)+"%") // mem used / requested
+	}
+
+	return row
+}
+
+// aggregateRecordHeader returns stable column keys of cpu and memory for csv/tsv output
+func (o *FreeOptions) aggregateRecordHeader() []string {
+
+	header := []string{}
+
+	if !o.noMetrics {
+		header = append(header, "cpu_used_millicores")
+	}
+	header = append(header, "cpu_requested_millicores", "cpu_limited_millicores")
+	if !o.noMetrics {
+		header = append(header, "cpu_used_percent")
+	}
+	header = append(header, "cpu_requested_percent", "cpu_limited_percent")
+	if !o.noMetrics {
+		header = append(header, "cpu_used_requested_percent")
+	}
+
+	if !o.noMetrics {
+		header = append(header, "memory_used_bytes")
+	}
+	header = append(header, "memory_requested_bytes", "memory_limited_bytes")
+	if !o.noMetrics {
+		header = append(header, "memory_used_percent")
+	}
+	header = append(header, "memory_requested_percent", "memory_limited_percent")
+	if !o.noMetrics {
+		header = append(header, "memory_used_requested_percent")
+	}
+
+	return header
+}
+
+// aggregateRecord returns raw values of cpu and memory for csv/tsv output
+func (o *FreeOptions) aggregateRecord(cpu, mem types.AggregateResource) []string {
+
+	i := func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	}
+
+	record := []string{}
+
+	if !o.noMetrics {
+		record = append(record, i(cpu.Used))
+	}
+	record = append(record, i(cpu.Requested), i(cpu.Limited))
+	if !o.noMetrics {
+		record = append(record, i(cpu.UsedPercent))
+	}
+	record = append(record, i(cpu.RequestedPercent), i(cpu.LimitedPercent))
+	if !o.noMetrics {
+		record = append(record, i(cpu.UsedRequestedPercent))
+	}
+
+	if !o.noMetrics {
+		record = append(record, i(mem.Used))
+	}
+	record = append(record, i(mem.Requested), i(mem.Limited))
+	if !o.noMetrics {
+		record = append(record, i(mem.UsedPercent))
+	}
+	record = append(record, i(mem.RequestedPercent), i(mem.LimitedPercent))
+	if !o.noMetrics {
+		record = append(record, i(mem.UsedRequestedPercent))
+	}
+
+	return record
+}
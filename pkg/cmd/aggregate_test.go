@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetNodesAllocatable(t *testing.T) {
+
+	cpu, mem := getNodesAllocatable(testNodes)
+
+	if cpu != 12000 || mem != 12000 {
+		t.Errorf("expected(12000, 12000) differ (got: %d, %d)", cpu, mem)
+		return
+	}
+
+	cpu, mem = getNodesAllocatable([]v1.Node{})
+
+	if cpu != 0 || mem != 0 {
+		t.Errorf("expected(0, 0) differ (got: %d, %d)", cpu, mem)
+		return
+	}
+}
+
+func TestSetAggregateResourcePercentage(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		allocatable int64
+		expected    types.AggregateResource
+	}{
+		{
+			"allocatable 1000",
+			1000,
+			types.AggregateResource{
+				Used:                 100,
+				Requested:            200,
+				Limited:              400,
+				UsedPercent:          10,
+				RequestedPercent:     20,
+				LimitedPercent:       40,
+				UsedRequestedPercent: 50,
+			},
+		},
+		{
+			"zero allocatable",
+			0,
+			types.AggregateResource{
+				Used:                 100,
+				Requested:            200,
+				Limited:              400,
+				UsedRequestedPercent: 50,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			actual := types.AggregateResource{}
+			addAggregateResource(&actual, types.ContainerResource{Used: 40, Requested: 150, Limited: 300})
+			addAggregateResource(&actual, types.ContainerResource{Used: 60, Requested: 50, Limited: 100})
+			setAggregateResourcePercentage(&actual, test.allocatable)
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestGetPodResource(t *testing.T) {
+
+	apps := []v1.Container{
+		testContainer("app1", 100, 200, 1000, 0),
+		testContainer("app2", 100, 0, 500, 0),
+	}
+	inits := []v1.Container{
+		testContainer("init1", 500, 500, 100, 0),
+		testContainer("init2", 300, 0, 200, 0),
+	}
+
+	overhead := testAggregatePod("default", "pod1", v1.PodRunning, nil, apps)
+	overhead.Spec.Overhead = v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(50, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(100, resource.DecimalSI),
+	}
+
+	metrics := util.ContainerMetricsIndex{
+		"default/pod1/app1":  testUsage(10, 0),
+		"default/pod1/app2":  testUsage(20, 0),
+		"default/pod1/init1": testUsage(5, 0),
+	}
+
+	var tests = []struct {
+		description string
+		pod         v1.Pod
+		expectedCPU types.ContainerResource
+		expectedMem types.ContainerResource
+	}{
+		{
+			"app containers only",
+			testAggregatePod("default", "pod1", v1.PodRunning, nil, apps),
+			types.ContainerResource{Used: 30, Requested: 200, Limited: 200},
+			types.ContainerResource{Requested: 1500},
+		},
+		{
+			"init container larger than app containers",
+			testAggregatePod("default", "pod1", v1.PodRunning, inits, apps),
+			types.ContainerResource{Used: 35, Requested: 500, Limited: 500},
+			types.ContainerResource{Requested: 1500},
+		},
+		{
+			"pod overhead",
+			overhead,
+			types.ContainerResource{Used: 30, Requested: 250, Limited: 250},
+			types.ContainerResource{Requested: 1600},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			cpu, mem := getPodResource(test.pod, metrics)
+
+			if cpu != test.expectedCPU {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expectedCPU, cpu)
+				return
+			}
+			if mem != test.expectedMem {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expectedMem, mem)
+				return
+			}
+		})
+	}
+}
+
+// testContainer returns a container with cpu (millicores) and memory (bytes) requests and limits
+// Zero values are not set.
+func testContainer(name string, cpuReq, cpuLim, memReq, memLim int64) v1.Container {
+
+	c := v1.Container{
+		Name: name,
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{},
+			Limits:   v1.ResourceList{},
+		},
+	}
+
+	if cpuReq > 0 {
+		c.Resources.Requests[v1.ResourceCPU] = *resource.NewMilliQuantity(cpuReq, resource.DecimalSI)
+	}
+	if cpuLim > 0 {
+		c.Resources.Limits[v1.ResourceCPU] = *resource.NewMilliQuantity(cpuLim, resource.DecimalSI)
+	}
+	if memReq > 0 {
+		c.Resources.Requests[v1.ResourceMemory] = *resource.NewQuantity(memReq, resource.DecimalSI)
+	}
+	if memLim > 0 {
+		c.Resources.Limits[v1.ResourceMemory] = *resource.NewQuantity(memLim, resource.DecimalSI)
+	}
+
+	return c
+}
+
+// testAggregatePod returns a pod on node1 in the phase with init containers and app containers
+func testAggregatePod(namespace, name string, phase v1.PodPhase, initContainers, containers []v1.Container) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: v1.PodSpec{
+			NodeName:       "node1",
+			InitContainers: initContainers,
+			Containers:     containers,
+		},
+		Status: v1.PodStatus{Phase: phase},
+	}
+}
+
+// testUsage returns usage of cpu (millicores) and memory (bytes) in metrics
+func testUsage(cpu, mem int64) v1.ResourceList {
+	return v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(mem, resource.DecimalSI),
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"github.com/makocchi-git/kubectl-free/pkg/collector"
+	"github.com/makocchi-git/kubectl-free/pkg/constants"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+)
+
+// startCollector starts watching nodes and pods until stop is closed (--watch and tui)
+// Resources are read from the cache of the collector instead of the API after this.
+func (o *FreeOptions) startCollector(args []string, stop <-chan struct{}) error {
+
+	// nodes specified by name are not filtered by the label selector as well as util.GetNodes
+	labelSelector := o.labelSelector
+	if len(args) > 0 {
+		labelSelector = ""
+	}
+
+	var metricsNodeClient metricsv1beta1.NodeMetricsInterface
+	var metricsPodClient metricsv1beta1.PodMetricsInterface
+	if !o.noMetrics {
+		metricsNodeClient, metricsPodClient = o.metricsNodeClient, o.metricsPodClient
+	}
+
+	c := collector.NewCollector(
+		o.nodeClient,
+		o.podClient,
+		metricsNodeClient,
+		metricsPodClient,
+		labelSelector,
+		o.countPhases,
+		o.interval,
+	)
+	if err := c.Start(stop); err != nil {
+		return err
+	}
+
+	o.collector = c
+
+	return nil
+}
+
+// getNodes returns nodes from the collector if it is started, otherwise from the API
+func (o *FreeOptions) getNodes(args []string) ([]v1.Node, error) {
+	if o.collector != nil {
+		return o.collector.GetNodes(args)
+	}
+	return util.GetNodes(o.nodeClient, args, o.labelSelector)
+}
+
+// listPods returns all pods from the collector if it is started, otherwise from the API
+func (o *FreeOptions) listPods() (*v1.PodList, error) {
+	if o.collector != nil {
+		return o.collector.GetPods(), nil
+	}
+	return util.ListPods(o.podClient, "", constants.PodListLimit)
+}
+
+// listPodsByNode returns pods on the nodes indexed by node name
+// Pods are listed per node by field selector if only a few nodes are given, otherwise all pods are listed once.
+func (o *FreeOptions) listPodsByNode(nodes []v1.Node) (map[string]v1.PodList, error) {
+
+	if o.collector != nil || len(nodes) > constants.PodListPerNodeMax {
+		pods, err := o.listPods()
+		if err != nil {
+			return nil, err
+		}
+		return util.GetPodsByNode(pods), nil
+	}
+
+	podsByNode := map[string]v1.PodList{}
+
+	for _, node := range nodes {
+		nodeName := node.ObjectMeta.Name
+		pods, err := util.ListPods(o.podClient, "spec.nodeName="+nodeName, constants.PodListLimit)
+		if err != nil {
+			return nil, err
+		}
+		podsByNode[nodeName] = util.GetPodsByNode(pods)[nodeName]
+	}
+
+	return podsByNode, nil
+}
+
+// listPodsOnNodes returns pods on the nodes in order of nodes
+func (o *FreeOptions) listPodsOnNodes(nodes []v1.Node) ([]v1.Pod, error) {
+
+	podsByNode, err := o.listPodsByNode(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := []v1.Pod{}
+	for _, node := range nodes {
+		pods = append(pods, podsByNode[node.ObjectMeta.Name].Items...)
+	}
+
+	return pods, nil
+}
+
+// getNodeAggregates returns sum of resources of pods counted by --count-phases on the nodes indexed by node name
+// The collector keeps them up to date, otherwise they are computed from listed pods.
+func (o *FreeOptions) getNodeAggregates(nodes []v1.Node) (map[string]collector.Aggregate, error) {
+
+	if o.collector != nil {
+		return o.collector.GetAggregates(), nil
+	}
+
+	podsByNode, err := o.listPodsByNode(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates := map[string]collector.Aggregate{}
+	for nodeName, pods := range podsByNode {
+		aggregates[nodeName] = collector.NewAggregates(&pods, o.countPhases)[nodeName]
+	}
+
+	return aggregates, nil
+}
+
+// listNodeMetrics returns node metrics, nil if metrics are disabled
+func (o *FreeOptions) listNodeMetrics() (*metricsapiv1beta1.NodeMetricsList, error) {
+
+	if o.noMetrics || o.metricsNodeClient == nil {
+		return nil, nil
+	}
+
+	if o.collector != nil {
+		return o.collector.GetNodeMetrics()
+	}
+
+	return o.metricsNodeClient.List(metav1.ListOptions{})
+}
+
+// listPodMetrics returns pod metrics, nil if metrics are disabled
+func (o *FreeOptions) listPodMetrics() (*metricsapiv1beta1.PodMetricsList, error) {
+
+	if o.noMetrics || o.metricsPodClient == nil {
+		return nil, nil
+	}
+
+	if o.collector != nil {
+		return o.collector.GetPodMetrics()
+	}
+
+	return o.metricsPodClient.List(metav1.ListOptions{})
+}
+
+// getContainerMetrics returns usage of containers indexed by namespace/pod/container
+// Usage is empty with a warning if pod metrics are not available.
+func (o *FreeOptions) getContainerMetrics() util.ContainerMetricsIndex {
+
+	podMetrics, err := o.listPodMetrics()
+	if err != nil {
+		o.printWarning("failed to list pod metrics: %v", err)
+		return util.NewContainerMetricsIndex(nil)
+	}
+
+	return util.NewContainerMetricsIndex(podMetrics)
+}
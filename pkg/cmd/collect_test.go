@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestStartCollector(t *testing.T) {
+
+	fakeNodeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1])
+	fakePodClient := fake.NewSimpleClientset(&testPods[0], &testPods[1], &testPods[2])
+
+	var tests = []struct {
+		description string
+		args        []string
+		list        bool
+	}{
+		{"nodes", []string{}, false},
+		{"node names", []string{"node2", "node1"}, false},
+		{"containers", []string{"node1"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			// same output with and without the collector
+			outputs := []string{}
+			for _, cached := range []bool{false, true} {
+				buffer := &bytes.Buffer{}
+				o := &FreeOptions{
+					nocolor:    true,
+					noMetrics:  true,
+					list:       test.list,
+					table:      table.NewOutputTable(buffer),
+					nodeClient: fakeNodeClient.CoreV1().Nodes(),
+					podClient:  fakePodClient.CoreV1().Pods(""),
+				}
+				o.prepareFreeTableHeader()
+				o.prepareListTableHeader()
+
+				if cached {
+					stop := make(chan struct{})
+					defer close(stop)
+					if err := o.startCollector(test.args, stop); err != nil {
+						t.Errorf("[%s] unexpected error: %v", test.description, err)
+						return
+					}
+				}
+
+				if err := o.run(test.args); err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+					return
+				}
+
+				// order of pods listed from the fake clientset is not stable
+				lines := strings.Split(buffer.String(), "\n")
+				sort.Strings(lines)
+				outputs = append(outputs, strings.Join(lines, "\n"))
+			}
+
+			if outputs[0] == "" || outputs[0] != outputs[1] {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, outputs[0], outputs[1])
+				return
+			}
+		})
+	}
+}
+
+func TestListPodsByNode(t *testing.T) {
+
+	nodes := func(n int) []v1.Node {
+		l := []v1.Node{}
+		for i := 1; i <= n; i++ {
+			l = append(l, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node" + strconv.Itoa(i)}})
+		}
+		return l
+	}
+
+	var tests = []struct {
+		description string
+		nodes       []v1.Node
+		selectors   []string
+		pods        map[string]int
+	}{
+		{
+			"per node",
+			nodes(2),
+			[]string{"spec.nodeName=node1", "spec.nodeName=node2"},
+			map[string]int{"node1": 3},
+		},
+		{
+			"all pods once",
+			nodes(6),
+			[]string{""},
+			map[string]int{"node1": 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			// fake clientset ignores field selectors, so they are recorded by reactor
+			selectors := []string{}
+			fakePodClient := fake.NewSimpleClientset(&testPods[0], &testPods[1], &testPods[2])
+			fakePodClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				selectors = append(selectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+				return false, nil, nil
+			})
+
+			o := &FreeOptions{podClient: fakePodClient.CoreV1().Pods("")}
+
+			podsByNode, err := o.listPodsByNode(test.nodes)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if !reflect.DeepEqual(selectors, test.selectors) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.selectors, selectors)
+				return
+			}
+
+			actual := map[string]int{}
+			for name, pods := range podsByNode {
+				if len(pods.Items) > 0 {
+					actual[name] = len(pods.Items)
+				}
+			}
+			if !reflect.DeepEqual(actual, test.pods) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.pods, actual)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/placement"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// consolidateLong defines long description of consolidate
+	consolidateLong = templates.LongDesc(`
+		Show a removable set of nodes (greedy) whose pods could be packed onto the other nodes.
+
+		Nodes whose cpu and memory req% are both below --threshold are tried in ascending order of req%.
+		Pods on a node except DaemonSet pods and mirror pods are placed first-fit-decreasing on the remaining nodes
+		by free resources (allocatable minus effective requests of pods in all namespaces) and scheduling constraints,
+		and the node is removed if all of them fit. Pods moved earlier are moved again if their new node is removed.
+		Nodes are removed greedily one by one, so the removable set is not always the largest one.
+
+		Nodes are blocked if they have pods not managed by a controller, pods with local storage (emptyDir or hostPath)
+		or pods whose PodDisruptionBudget does not allow more disruptions. Kept nodes show all of their reasons.
+	`)
+
+	// consolidateExample defines command examples of consolidate
+	consolidateExample = templates.Examples(`
+		# Show a removable set of nodes and req% of the remaining nodes.
+		kubectl free consolidate
+
+		# Try nodes whose cpu and memory req% are below 30% among nodes labeled as workers.
+		kubectl free consolidate --threshold 30 -l node-role=worker
+
+		# Print only names of nodes in the removable set.
+		kubectl free consolidate --names-only
+	`)
+)
+
+// consolidateOptions is options of consolidate subcommand
+type consolidateOptions struct {
+	*FreeOptions
+
+	// threshold of cpu and memory req% of nodes to be tried to remove
+	threshold int64
+}
+
+// consolidateNode is a node with the result of consolidation
+type consolidateNode struct {
+	node types.Node
+
+	// removable is true if pods on the node can be placed on the other nodes
+	removable bool
+
+	// cpu and memory req% after consolidation
+	cpuPercent int64
+	memPercent int64
+
+	// reasons why the node is kept
+	reasons []string
+}
+
+// NewCmdConsolidate is a cobra command of consolidation of nodes
+func NewCmdConsolidate(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+
+	co := &consolidateOptions{
+		FreeOptions: o,
+		threshold:   50,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "consolidate [node...]",
+		Short:   "Show a removable set of nodes (greedy) whose pods could be packed onto the other nodes.",
+		Long:    consolidateLong,
+		Example: consolidateExample,
+		Run: func(c *cobra.Command, args []string) {
+			// free resources are computed from pods in all namespaces
+			o.allNamespaces = true
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(co.Validate())
+			cmdutil.CheckErr(co.Run(args))
+		},
+	}
+
+	cmd.Flags().Int64VarP(&co.threshold, "threshold", "", co.threshold, `Nodes whose cpu and memory req% are both below the threshold are tried to remove.`)
+	o.addFlags(cmd.Flags(), "no-headers", "names-only")
+
+	return cmd
+}
+
+// Validate ensures that options are supported by consolidate
+func (co *consolidateOptions) Validate() error {
+
+	if co.output != "" {
+		return fmt.Errorf("consolidate supports only table output (got: %s)", co.output)
+	}
+
+	if co.threshold <= 0 || co.threshold > 100 {
+		return fmt.Errorf("--threshold must be between 1 and 100 (got: %d)", co.threshold)
+	}
+
+	return nil
+}
+
+// Run shows nodes which could be removed
+func (co *consolidateOptions) Run(args []string) error {
+
+	// usage is not considered by the scheduler
+	co.noMetrics = true
+
+	nodes, err := co.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	pods, err := co.listPods()
+	if err != nil {
+		return err
+	}
+
+	pdbs, err := co.policyClient.PodDisruptionBudgets("").List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list poddisruptionbudgets: %v", err)
+	}
+
+	return co.showConsolidate(nodes, pods, pdbs.Items)
+}
+
+// showConsolidate prints nodes with whether they could be removed and their req% after consolidation
+func (co *consolidateOptions) showConsolidate(nodes []v1.Node, pods *v1.PodList, pdbs []policyv1beta1.PodDisruptionBudget) error {
+
+	counted := util.FilterPods(*pods, co.countPhases)
+
+	// extended resources requested by pods are collected as --resources does
+	requests := map[v1.ResourceName]int64{}
+	for _, pod := range counted.Items {
+		for name, v := range placement.PodRequests(pod) {
+			requests[name] += v
+		}
+	}
+	co.resourceNames = extendedResourceNames(requests)
+
+	items, err := co.getNodeResourcesFromPods(nodes, pods)
+	if err != nil {
+		return err
+	}
+
+	result := co.consolidate(nodes, items, util.GetPodsByNode(&counted), pdbs)
+
+	// print names of nodes in the removable set (--names-only option)
+	if co.namesOnly {
+		names := []string{}
+		for _, c := range result {
+			if c.removable {
+				names = append(names, c.node.Name)
+			}
+		}
+		co.printNames(names)
+		return nil
+	}
+
+	if !co.noHeaders {
+		co.table.Header = co.consolidateTableHeader()
+	}
+
+	removable := []string{}
+	for _, c := range result {
+		co.table.AddRow(co.consolidateTableRow(c))
+		if c.removable {
+			removable = append(removable, c.node.Name)
+		}
+	}
+
+	co.table.Print()
+
+	if co.noHeaders {
+		return nil
+	}
+
+	if len(removable) == 0 {
+		fmt.Fprintf(co.table.Output, "\nremovable set (greedy): 0 of %d nodes\n", len(result))
+	} else {
+		fmt.Fprintf(co.table.Output, "\nremovable set (greedy): %d of %d nodes: %s\n", len(removable), len(result), strings.Join(removable, ", "))
+	}
+
+	return nil
+}
+
+// consolidate removes under-utilized nodes one by one if their pods fit on the remaining nodes
+// items are resources of nodes in the same order as nodes, and results are in the same order too.
+// Kept nodes have all reasons, blocked nodes are also checked whether their pods fit.
+func (co *consolidateOptions) consolidate(nodes []v1.Node, items []types.Node, podsByNode map[string]v1.PodList, pdbs []policyv1beta1.PodDisruptionBudget) []consolidateNode {
+
+	result := []consolidateNode{}
+	state := []placement.Node{}
+	candidates := []int{}
+
+	for i, item := range items {
+		result = append(result, consolidateNode{node: item})
+		state = append(state, placement.Node{
+			Node: nodes[i],
+			Free: freeResources(item),
+			Pods: podsByNode[item.Name].Items,
+		})
+
+		if item.CPU.RequestedPercent < co.threshold && item.Memory.RequestedPercent < co.threshold {
+			candidates = append(candidates, i)
+		} else {
+			result[i].reasons = []string{"above threshold"}
+		}
+	}
+
+	// less utilized nodes are tried first
+	sort.SliceStable(candidates, func(a, b int) bool {
+		na, nb := items[candidates[a]], items[candidates[b]]
+		return na.CPU.RequestedPercent+na.Memory.RequestedPercent < nb.CPU.RequestedPercent+nb.Memory.RequestedPercent
+	})
+
+	// disruptions allowed per PodDisruptionBudget
+	allowed := map[string]int32{}
+	for _, pdb := range pdbs {
+		allowed[pdb.ObjectMeta.Namespace+"/"+pdb.ObjectMeta.Name] = pdb.Status.PodDisruptionsAllowed
+	}
+
+	removed := map[int]bool{}
+
+	for _, i := range candidates {
+		// pods moved from removed nodes are included
+		evictable, unmanaged := getEvictablePods(state[i].Pods)
+
+		// pods moved from removed nodes are already counted
+		evictions := map[string]int32{}
+		for _, pod := range evictable {
+			if pod.Spec.NodeName == result[i].node.Name {
+				getPodDisruptionBudget(pdbs, pod, evictions)
+			}
+		}
+
+		reasons := getBlockedReasons(evictable, unmanaged, evictions, allowed)
+
+		// place pods on copies of the remaining nodes
+		targets, indexes := []placement.Node{}, []int{}
+		for j := range state {
+			if j != i && !removed[j] {
+				targets = append(targets, state[j].DeepCopy())
+				indexes = append(indexes, j)
+			}
+		}
+
+		pending := 0
+		for _, p := range placement.Place(evictable, targets) {
+			if p.Node == "" {
+				pending++
+			}
+		}
+
+		if pending > 0 {
+			reasons = append(reasons, fmt.Sprintf("pods do not fit (%d)", pending))
+		}
+
+		if len(reasons) > 0 {
+			result[i].reasons = reasons
+			continue
+		}
+
+		for k, j := range indexes {
+			state[j] = targets[k]
+		}
+		for name, n := range evictions {
+			allowed[name] -= n
+		}
+
+		removed[i] = true
+		result[i].removable = true
+	}
+
+	for i := range result {
+		if removed[i] {
+			continue
+		}
+		n := result[i].node
+		result[i].cpuPercent = util.GetPercentage(n.CPU.Allocatable-state[i].Free[v1.ResourceCPU], n.CPU.Allocatable)
+		result[i].memPercent = util.GetPercentage(n.Memory.Allocatable-state[i].Free[v1.ResourceMemory], n.Memory.Allocatable)
+	}
+
+	return result
+}
+
+// getBlockedReasons returns reasons why pods on a node can not be moved
+// evictions are numbers of evicted pods per PodDisruptionBudget and allowed are numbers of allowed disruptions.
+func getBlockedReasons(evictable, unmanaged []v1.Pod, evictions, allowed map[string]int32) []string {
+
+	reasons := []string{}
+
+	if len(unmanaged) > 0 {
+		reasons = append(reasons, fmt.Sprintf("not replicated (%d)", len(unmanaged)))
+	}
+
+	local := 0
+	for _, pod := range evictable {
+		if hasLocalStorage(pod) {
+			local++
+		}
+	}
+	if local > 0 {
+		reasons = append(reasons, fmt.Sprintf("local storage (%d)", local))
+	}
+
+	names := []string{}
+	for name, n := range evictions {
+		if n > allowed[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		reasons = append(reasons, "poddisruptionbudget "+name)
+	}
+
+	return reasons
+}
+
+// hasLocalStorage returns true if the pod has emptyDir or hostPath volumes which are lost by eviction
+func hasLocalStorage(pod v1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil || volume.HostPath != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// consolidateTableHeader returns table headers of consolidate
+func (co *consolidateOptions) consolidateTableHeader() []string {
+
+	hStatus := "STATUS"
+	hCPUReqP := "CPU/req%"
+	hMEMReqP := "MEM/req%"
+	hCPUAfterP := "CPU/after%"
+	hMEMAfterP := "MEM/after%"
+
+	if !co.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hStatus)    // STATUS
+		util.DefaultColor(&hCPUReqP)   // CPU/req%
+		util.DefaultColor(&hMEMReqP)   // MEM/req%
+		util.DefaultColor(&hCPUAfterP) // CPU/after%
+		util.DefaultColor(&hMEMAfterP) // MEM/after%
+	}
+
+	return []string{"NAME", hStatus, hCPUReqP, hMEMReqP, hCPUAfterP, hMEMAfterP, "ACTION", "REASON"}
+}
+
+// consolidateTableRow returns table row of a node
+func (co *consolidateOptions) consolidateTableRow(c consolidateNode) []string {
+
+	n := c.node
+
+	status := n.Status
+	if co.emojiStatus {
+		status = util.GetNodeStatusEmoji(status)
+	}
+	util.SetNodeStatusColor(&status, co.nocolor)
+
+	cpuAfter, memAfter, action, reason := "-", "-", "remove", "-"
+	if c.removable {
+		if !co.nocolor {
+			// hack: avoid breaking column by escape char
+			util.DefaultColor(&cpuAfter)
+			util.DefaultColor(&memAfter)
+		}
+	} else {
+		cpuAfter = co.toColorPercent(c.cpuPercent)
+		memAfter = co.toColorPercent(c.memPercent)
+		action = "keep"
+		reason = strings.Join(c.reasons, ", ")
+	}
+
+	return []string{
+		n.Name,
+		status,
+		co.toColorPercent(n.CPU.RequestedPercent),
+		co.toColorPercent(n.Memory.RequestedPercent),
+		cpuAfter,
+		memAfter,
+		action,
+		reason,
+	}
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConsolidateValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		threshold   int64
+		output      string
+		expected    string
+	}{
+		{"default", 50, "", ""},
+		{"zero", 0, "", "--threshold must be between 1 and 100 (got: 0)"},
+		{"over 100", 101, "", "--threshold must be between 1 and 100 (got: 101)"},
+		{"output", 50, "json", "consolidate supports only table output (got: json)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			co := &consolidateOptions{
+				FreeOptions: &FreeOptions{output: test.output},
+				threshold:   test.threshold,
+			}
+
+			err := co.Validate()
+			if test.expected == "" {
+				if err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+				return
+			}
+		})
+	}
+}
+
+func TestShowConsolidate(t *testing.T) {
+
+	node2 := testNodes[1].DeepCopy()
+	node2.Status.Conditions[0].Status = v1.ConditionTrue
+	node3 := testNodes[0].DeepCopy()
+	node3.ObjectMeta.Name = "node3"
+	node4 := testNodes[0].DeepCopy()
+	node4.ObjectMeta.Name = "node4"
+
+	onNode := func(pod v1.Pod, node string) v1.Pod {
+		pod.Spec.NodeName = node
+		return pod
+	}
+
+	a := testDrainPod("a", 1000, "ReplicaSet", map[string]string{"app": "a"})
+	b := onNode(testDrainPod("b", 3000, "ReplicaSet", map[string]string{"app": "b"}), "node2")
+	c := onNode(testDrainPod("c", 3500, "ReplicaSet", nil), "node3")
+	d := onNode(testDrainPod("d", 200, "", nil), "node4")
+	e := onNode(testDrainPod("e", 100, "ReplicaSet", nil), "node4")
+	e.Spec.Containers[0].Resources.Requests[v1.ResourceMemory] = *resource.NewQuantity(500, resource.DecimalSI)
+	e.Spec.Volumes = []v1.Volume{{Name: "cache", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}
+	e.Spec.NodeSelector = map[string]string{"hostname": "node4"}
+
+	pdb := policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"},
+		Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}}},
+		Status:     policyv1beta1.PodDisruptionBudgetStatus{PodDisruptionsAllowed: 0},
+	}
+
+	var tests = []struct {
+		description string
+		pdbs        []policyv1beta1.PodDisruptionBudget
+		namesOnly   bool
+		expected    []string
+	}{
+		{
+			"consolidate",
+			[]policyv1beta1.PodDisruptionBudget{},
+			false,
+			[]string{
+				"NAME    STATUS   CPU/req%   MEM/req%   CPU/after%   MEM/after%   ACTION   REASON",
+				"node1   Ready    25%        25%        100%         50%          keep     pods do not fit (1)",
+				"node2   Ready    37%        12%        -            -            remove   -",
+				"node3   Ready    87%        25%        87%          25%          keep     above threshold",
+				"node4   Ready    7%         37%        7%           37%          keep     not replicated (1), local storage (1), pods do not fit (1)",
+				"",
+				"removable set (greedy): 1 of 4 nodes: node2",
+				"",
+			},
+		},
+		{
+			"blocked by pdb",
+			[]policyv1beta1.PodDisruptionBudget{pdb},
+			false,
+			[]string{
+				"NAME    STATUS   CPU/req%   MEM/req%   CPU/after%   MEM/after%   ACTION   REASON",
+				"node1   Ready    25%        25%        -            -            remove   -",
+				"node2   Ready    37%        12%        50%          25%          keep     poddisruptionbudget default/b",
+				"node3   Ready    87%        25%        87%          25%          keep     above threshold",
+				"node4   Ready    7%         37%        7%           37%          keep     not replicated (1), local storage (1), pods do not fit (1)",
+				"",
+				"removable set (greedy): 1 of 4 nodes: node1",
+				"",
+			},
+		},
+		{
+			"names only",
+			[]policyv1beta1.PodDisruptionBudget{},
+			true,
+			[]string{"node2", ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(&a, &b, &c, &d, &e)
+
+			buffer := &bytes.Buffer{}
+			co := &consolidateOptions{
+				FreeOptions: &FreeOptions{
+					table:     table.NewOutputTable(buffer),
+					nocolor:   true,
+					noMetrics: true,
+					namesOnly: test.namesOnly,
+					podClient: fakePodClient.CoreV1().Pods(""),
+				},
+				threshold: 50,
+			}
+
+			pods, err := co.listPods()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			nodes := []v1.Node{testNodes[0], *node2, *node3, *node4}
+			if err := co.showConsolidate(nodes, pods, test.pdbs); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			// pods are listed once
+			if calls := countListPods(fakePodClient); calls != 1 {
+				t.Errorf("[%s] expected(1) list calls differ (got: %d)", test.description, calls)
+				return
+			}
+
+			actual := strings.Split(buffer.String(), "\n")
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
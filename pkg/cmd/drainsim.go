@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/makocchi-git/kubectl-free/pkg/placement"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// drainSimLong defines long description of drain-sim
+	drainSimLong = templates.LongDesc(`
+		Simulate draining nodes and show where their pods would be re-scheduled.
+
+		Pods on the drained nodes except DaemonSet pods and mirror pods are evicted, and they are placed
+		first-fit-decreasing (larger cpu and memory requests first) on the remaining nodes by free resources
+		(allocatable minus effective requests of pods in all namespaces) and scheduling constraints.
+		Pods not managed by a controller are reported as they would be deleted and never come back.
+
+		PodDisruptionBudgets are not enforced but reported if evictions exceed allowed disruptions.
+		Remaining nodes can be narrowed by --selector.
+	`)
+
+	// drainSimExample defines command examples of drain-sim
+	drainSimExample = templates.Examples(`
+		# Simulate draining node1 and node2.
+		kubectl free drain-sim node1 node2
+
+		# Simulate draining node1 and re-scheduling its pods only on nodes labeled as workers.
+		kubectl free drain-sim node1 -l node-role=worker
+	`)
+)
+
+// annotationMirrorPod is the annotation of static pods mirrored by kubelet
+const annotationMirrorPod = "kubernetes.io/config.mirror"
+
+// drainSimOptions is options of drain-sim subcommand
+type drainSimOptions struct {
+	*FreeOptions
+}
+
+// NewCmdDrainSim is a cobra command of drain simulation of nodes
+func NewCmdDrainSim(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+
+	do := &drainSimOptions{
+		FreeOptions: o,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "drain-sim NODE [node...]",
+		Short:   "Simulate draining nodes and show where their pods would be re-scheduled.",
+		Long:    drainSimLong,
+		Example: drainSimExample,
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			// free resources are computed from pods in all namespaces
+			o.allNamespaces = true
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(do.Validate())
+			cmdutil.CheckErr(do.Run(args))
+		},
+	}
+
+	o.addFlags(cmd.Flags(), "no-headers")
+
+	return cmd
+}
+
+// Validate ensures that options are supported by drain-sim
+func (do *drainSimOptions) Validate() error {
+
+	if do.output != "" {
+		return fmt.Errorf("drain-sim supports only table output (got: %s)", do.output)
+	}
+
+	if do.namesOnly {
+		return fmt.Errorf("--names-only is not supported with drain-sim")
+	}
+
+	return nil
+}
+
+// Run shows the placement plan of pods on the drained nodes
+func (do *drainSimOptions) Run(args []string) error {
+
+	// usage is not considered by the scheduler
+	do.noMetrics = true
+
+	drained, err := do.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := do.getNodes([]string{})
+	if err != nil {
+		return err
+	}
+
+	pods, err := do.listPods()
+	if err != nil {
+		return err
+	}
+
+	pdbs, err := do.policyClient.PodDisruptionBudgets("").List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list poddisruptionbudgets: %v", err)
+	}
+
+	return do.showDrainSim(drained, nodes, pods, pdbs.Items)
+}
+
+// showDrainSim prints where pods on the drained nodes would be placed on the other nodes
+func (do *drainSimOptions) showDrainSim(drained, nodes []v1.Node, pods *v1.PodList, pdbs []policyv1beta1.PodDisruptionBudget) error {
+
+	drainedNames := map[string]bool{}
+	for _, n := range drained {
+		drainedNames[n.ObjectMeta.Name] = true
+	}
+
+	targets := []v1.Node{}
+	for _, n := range nodes {
+		if !drainedNames[n.ObjectMeta.Name] {
+			targets = append(targets, n)
+		}
+	}
+
+	counted := util.FilterPods(*pods, do.countPhases)
+	podsByNode := util.GetPodsByNode(&counted)
+
+	evicted, unmanaged := []v1.Pod{}, []v1.Pod{}
+	for _, n := range drained {
+		e, u := getEvictablePods(podsByNode[n.ObjectMeta.Name].Items)
+		evicted = append(evicted, e...)
+		unmanaged = append(unmanaged, u...)
+	}
+
+	placements, err := do.placePods(evicted, targets, &counted)
+	if err != nil {
+		return err
+	}
+
+	// evictions per PodDisruptionBudget
+	evictions := map[string]int32{}
+
+	if !do.noHeaders {
+		do.table.Header = []string{"NAMESPACE", "POD", "NODE", "CPU/req", "MEM/req", "TARGET", "PDB", "REASON"}
+	}
+
+	pending := 0
+	for _, p := range placements {
+		target, reason := p.Node, "-"
+		if target == "" {
+			target = "-"
+			reason = formatReasons(p.Reasons)
+			if len(p.Reasons) == 0 {
+				reason = "no nodes"
+			}
+			pending++
+		}
+		do.table.AddRow(do.drainSimTableRow(p.Pod, target, getPodDisruptionBudget(pdbs, p.Pod, evictions), reason))
+	}
+
+	for _, pod := range unmanaged {
+		do.table.AddRow(do.drainSimTableRow(pod, "-", getPodDisruptionBudget(pdbs, pod, evictions), "not managed by a controller"))
+	}
+
+	do.table.Print()
+
+	if do.noHeaders {
+		return nil
+	}
+
+	fmt.Fprintf(do.table.Output, "\n%d pods evicted from %d nodes: %d re-scheduled, %d do not fit\n", len(placements), len(drained), len(placements)-pending, pending)
+
+	if len(unmanaged) > 0 {
+		fmt.Fprintf(do.table.Output, "%d pods are not managed by a controller and would be deleted\n", len(unmanaged))
+	}
+
+	for _, pdb := range pdbs {
+		name := pdb.ObjectMeta.Namespace + "/" + pdb.ObjectMeta.Name
+		if n := evictions[name]; n > pdb.Status.PodDisruptionsAllowed {
+			fmt.Fprintf(do.table.Output, "poddisruptionbudget %s allows %d disruptions but %d pods would be evicted\n", name, pdb.Status.PodDisruptionsAllowed, n)
+		}
+	}
+
+	return nil
+}
+
+// placePods places pods on target nodes by their free resources and pods
+// Free resources are computed from counted pods already listed.
+func (do *drainSimOptions) placePods(pods []v1.Pod, targets []v1.Node, counted *v1.PodList) ([]placement.Placement, error) {
+
+	// extended resources requested by the pods are collected as --resources does
+	requests := map[v1.ResourceName]int64{}
+	for _, pod := range pods {
+		for name, v := range placement.PodRequests(pod) {
+			requests[name] += v
+		}
+	}
+	do.resourceNames = extendedResourceNames(requests)
+
+	items, err := do.getNodeResourcesFromPods(targets, counted)
+	if err != nil {
+		return nil, err
+	}
+
+	podsByNode := util.GetPodsByNode(counted)
+
+	nodes := []placement.Node{}
+	for i, item := range items {
+		nodes = append(nodes, placement.Node{
+			Node: targets[i],
+			Free: freeResources(item),
+			Pods: podsByNode[item.Name].Items,
+		})
+	}
+
+	return placement.Place(pods, nodes), nil
+}
+
+// getEvictablePods returns pods which would be evicted by drain and pods not managed by a controller
+// DaemonSet pods and mirror pods are skipped as drain ignores them.
+func getEvictablePods(pods []v1.Pod) ([]v1.Pod, []v1.Pod) {
+
+	evictable, unmanaged := []v1.Pod{}, []v1.Pod{}
+
+	for _, pod := range pods {
+		if _, ok := pod.ObjectMeta.Annotations[annotationMirrorPod]; ok {
+			continue
+		}
+
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil {
+			unmanaged = append(unmanaged, pod)
+			continue
+		}
+
+		if owner.Kind == "DaemonSet" {
+			continue
+		}
+
+		evictable = append(evictable, pod)
+	}
+
+	return evictable, unmanaged
+}
+
+// getPodDisruptionBudget returns namespace/name of the PodDisruptionBudget matched with the pod and counts the eviction
+// "-" is returned if no PodDisruptionBudget covers the pod.
+func getPodDisruptionBudget(pdbs []policyv1beta1.PodDisruptionBudget, pod v1.Pod, evictions map[string]int32) string {
+
+	for _, pdb := range pdbs {
+		if pdb.ObjectMeta.Namespace != pod.ObjectMeta.Namespace {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		// an empty selector matches no pods in policy/v1beta1
+		if err != nil || selector.Empty() {
+			continue
+		}
+
+		if selector.Matches(labels.Set(pod.ObjectMeta.Labels)) {
+			name := pdb.ObjectMeta.Namespace + "/" + pdb.ObjectMeta.Name
+			evictions[name]++
+			return name
+		}
+	}
+
+	return "-"
+}
+
+// drainSimTableRow returns table row of an evicted pod
+func (do *drainSimOptions) drainSimTableRow(pod v1.Pod, target, pdb, reason string) []string {
+
+	requests := placement.PodRequests(pod)
+
+	return []string{
+		pod.ObjectMeta.Namespace,
+		pod.ObjectMeta.Name,
+		pod.Spec.NodeName,
+		do.toMilliUnitOrDash(requests[v1.ResourceCPU]),
+		do.toUnitOrDash(requests[v1.ResourceMemory]),
+		target,
+		pdb,
+		reason,
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+// testDrainPod returns a running pod on node1 requesting cpu in millicores and 1000 bytes memory
+// The pod is controlled by ownerKind if it is not empty.
+func testDrainPod(name string, cpu int64, ownerKind string, podLabels map[string]string) v1.Pod {
+
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    podLabels,
+		},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+			Containers: []v1.Container{
+				{
+					Name: name,
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+
+	if ownerKind != "" {
+		controller := true
+		pod.ObjectMeta.OwnerReferences = []metav1.OwnerReference{
+			{Kind: ownerKind, Name: name, Controller: &controller},
+		}
+	}
+
+	return pod
+}
+
+func TestGetEvictablePods(t *testing.T) {
+
+	mirror := testDrainPod("static", 100, "", nil)
+	mirror.ObjectMeta.Annotations = map[string]string{annotationMirrorPod: "hash"}
+
+	pods := []v1.Pod{
+		testDrainPod("web", 100, "ReplicaSet", nil),
+		testDrainPod("db", 100, "StatefulSet", nil),
+		testDrainPod("agent", 100, "DaemonSet", nil),
+		testDrainPod("debug", 100, "", nil),
+		mirror,
+	}
+
+	evictable, unmanaged := getEvictablePods(pods)
+
+	names := func(pods []v1.Pod) []string {
+		n := []string{}
+		for _, p := range pods {
+			n = append(n, p.ObjectMeta.Name)
+		}
+		return n
+	}
+
+	if actual, expected := names(evictable), []string{"web", "db"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+
+	if actual, expected := names(unmanaged), []string{"debug"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestShowDrainSim(t *testing.T) {
+
+	node2 := testNodes[1].DeepCopy()
+	node2.Status.Conditions[0].Status = v1.ConditionTrue
+	node3 := testNodes[0].DeepCopy()
+	node3.ObjectMeta.Name = "node3"
+	node3.Status.Conditions[0].Status = v1.ConditionFalse
+
+	web := map[string]string{"app": "web"}
+	web1 := testDrainPod("web-1", 3000, "ReplicaSet", web)
+	web2 := testDrainPod("web-2", 6000, "ReplicaSet", web)
+	agent := testDrainPod("agent", 100, "DaemonSet", nil)
+	debug := testDrainPod("debug", 100, "", nil)
+
+	pdb := func(allowed int32) policyv1beta1.PodDisruptionBudget {
+		return policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: web}},
+			Status:     policyv1beta1.PodDisruptionBudgetStatus{PodDisruptionsAllowed: allowed},
+		}
+	}
+
+	rows := []string{
+		"NAMESPACE   POD     NODE    CPU/req   MEM/req   TARGET   PDB           REASON",
+		"default     web-2   node1   6         1K        node2    default/web   -",
+		"default     web-1   node1   3         1K        -        default/web   insufficient cpu (1), not ready (1)",
+		"default     debug   node1   100m      1K        -        -             not managed by a controller",
+		"",
+		"2 pods evicted from 1 nodes: 1 re-scheduled, 1 do not fit",
+		"1 pods are not managed by a controller and would be deleted",
+	}
+
+	var tests = []struct {
+		description string
+		pdbs        []policyv1beta1.PodDisruptionBudget
+		expected    []string
+	}{
+		{
+			"disruptions allowed",
+			[]policyv1beta1.PodDisruptionBudget{pdb(2)},
+			append(append([]string{}, rows...), ""),
+		},
+		{
+			"disruptions exceeded",
+			[]policyv1beta1.PodDisruptionBudget{pdb(1)},
+			append(append([]string{}, rows...), "poddisruptionbudget default/web allows 1 disruptions but 2 pods would be evicted", ""),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(&web1, &web2, &agent, &debug)
+
+			buffer := &bytes.Buffer{}
+			do := &drainSimOptions{
+				FreeOptions: &FreeOptions{
+					table:     table.NewOutputTable(buffer),
+					nocolor:   true,
+					noMetrics: true,
+					podClient: fakePodClient.CoreV1().Pods(""),
+				},
+			}
+
+			pods, err := do.listPods()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			nodes := []v1.Node{testNodes[0], *node2, *node3}
+			if err := do.showDrainSim(nodes[:1], nodes, pods, test.pdbs); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			// pods are listed once
+			if calls := countListPods(fakePodClient); calls != 1 {
+				t.Errorf("[%s] expected(1) list calls differ (got: %d)", test.description, calls)
+				return
+			}
+
+			actual := strings.Split(buffer.String(), "\n")
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/placement"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// fitLong defines long description of fit
+	fitLong = templates.LongDesc(`
+		Show nodes which can host a pod and how many copies of the pod each node can host.
+
+		Free resources of nodes are allocatable minus effective requests of pods in all namespaces.
+
+		Readiness, cordon, taints, nodeSelector and required node affinity of the pod are honored.
+		Required pod anti-affinity of the pod with itself (topology key kubernetes.io/hostname) allows one copy per node.
+		Other inter-pod affinity, host ports and volumes are not checked.
+	`)
+
+	// fitExample defines command examples of fit
+	fitExample = templates.Examples(`
+		# Show nodes which can host a pod requesting 2 cpu and 8Gi memory.
+		kubectl free fit --cpu 2 --memory 8Gi
+
+		# Check whether 5 replicas can be scheduled now.
+		kubectl free fit --cpu 500m --memory 1Gi --replicas 5
+
+		# Use requests, tolerations, nodeSelector and node affinity of a pod manifest.
+		kubectl free fit --from-file pod.yaml
+	`)
+)
+
+// fitOptions is options of fit subcommand
+type fitOptions struct {
+	*FreeOptions
+
+	cpu      string
+	memory   string
+	replicas int64
+	filename string
+}
+
+// fitNode is a node with copies of the pod it can host
+type fitNode struct {
+	node    types.Node
+	copies  int64
+	reasons []string
+}
+
+// NewCmdFit is a cobra command of scheduling check for a hypothetical pod
+func NewCmdFit(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+
+	fo := &fitOptions{
+		FreeOptions: o,
+		cpu:         "",
+		memory:      "",
+		replicas:    1,
+		filename:    "",
+	}
+
+	cmd := &cobra.Command{
+		Use:     "fit [node...]",
+		Short:   "Show nodes which can host a pod.",
+		Long:    fitLong,
+		Example: fitExample,
+		Run: func(c *cobra.Command, args []string) {
+			// free resources are computed from pods in all namespaces
+			o.allNamespaces = true
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(fo.Validate())
+			cmdutil.CheckErr(fo.Run(args))
+		},
+	}
+
+	cmd.Flags().StringVarP(&fo.cpu, "cpu", "", fo.cpu, `CPU request of the pod (e.g. 500m, 2).`)
+	cmd.Flags().StringVarP(&fo.memory, "memory", "", fo.memory, `Memory request of the pod (e.g. 512Mi, 8Gi).`)
+	cmd.Flags().Int64VarP(&fo.replicas, "replicas", "", fo.replicas, `Number of replicas of the pod to be scheduled.`)
+	cmd.Flags().StringVarP(&fo.filename, "from-file", "", fo.filename, `Pod manifest (yaml or json) to read requests, tolerations, nodeSelector and node affinity from.`)
+	o.addFlags(cmd.Flags(), "ephemeral-storage", "no-headers", "names-only")
+
+	return cmd
+}
+
+// Validate ensures that the pod is given by --cpu and --memory, or --from-file
+func (fo *fitOptions) Validate() error {
+
+	if fo.output != "" {
+		return fmt.Errorf("fit supports only table output (got: %s)", fo.output)
+	}
+
+	if fo.replicas < 1 {
+		return fmt.Errorf("--replicas must be greater than 0 (got: %d)", fo.replicas)
+	}
+
+	if fo.filename != "" {
+		if fo.cpu != "" || fo.memory != "" {
+			return fmt.Errorf("--from-file can not be used with --cpu and --memory")
+		}
+		return nil
+	}
+
+	if fo.cpu == "" && fo.memory == "" {
+		return fmt.Errorf("--cpu, --memory or --from-file is required")
+	}
+
+	_, err := fo.pod()
+	return err
+}
+
+// Run shows nodes which can host the pod
+func (fo *fitOptions) Run(args []string) error {
+
+	// usage is not considered by the scheduler
+	fo.noMetrics = true
+
+	nodes, err := fo.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	pod, err := fo.pod()
+	if err != nil {
+		return err
+	}
+
+	return fo.showFit(nodes, pod)
+}
+
+// pod returns the pod of --from-file, or the pod which has a container requesting --cpu and --memory
+func (fo *fitOptions) pod() (v1.Pod, error) {
+
+	if fo.filename != "" {
+		return readPod(fo.filename)
+	}
+
+	requests := v1.ResourceList{}
+	for name, s := range map[v1.ResourceName]string{v1.ResourceCPU: fo.cpu, v1.ResourceMemory: fo.memory} {
+		if s == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(s)
+		if err != nil {
+			return v1.Pod{}, fmt.Errorf("invalid --%s: %v", name, err)
+		}
+		requests[name] = q
+	}
+
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "fit"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name:      "fit",
+					Resources: v1.ResourceRequirements{Requests: requests},
+				},
+			},
+		},
+	}
+
+	return pod, nil
+}
+
+// readPod reads a pod manifest from yaml or json file
+func readPod(filename string) (v1.Pod, error) {
+
+	pod := v1.Pod{}
+
+	manifest, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return pod, err
+	}
+
+	if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096).Decode(&pod); err != nil {
+		return pod, fmt.Errorf("failed to read %s: %v", filename, err)
+	}
+
+	if pod.Kind != "Pod" {
+		return pod, fmt.Errorf("%s is not a Pod (kind: %s)", filename, pod.Kind)
+	}
+
+	// restartPolicy of init containers is not decoded into the pod
+	util.SetRestartableInitContainers(&pod, manifest)
+
+	return pod, nil
+}
+
+// showFit prints free resources of nodes and how many copies of the pod each node can host
+func (fo *fitOptions) showFit(nodes []v1.Node, pod v1.Pod) error {
+
+	requests := placement.PodRequests(pod)
+
+	// extended resources requested by the pod are collected as --resources does
+	fo.resourceNames = extendedResourceNames(requests)
+
+	items, err := fo.getNodeResources(nodes)
+	if err != nil {
+		return err
+	}
+
+	fits := getFitNodes(nodes, items, pod, requests)
+
+	antiAffinity, err := placement.AntiAffinitySelectors(pod.Spec)
+	if err != nil {
+		return err
+	}
+
+	// copies are spread one per node if they repel each other
+	if placement.MatchesAny(antiAffinity, pod.ObjectMeta.Labels) {
+		for i := range fits {
+			if fits[i].copies > 1 {
+				fits[i].copies = 1
+			}
+		}
+	}
+
+	// print names of nodes which can host the pod (--names-only option)
+	if fo.namesOnly {
+		names := []string{}
+		for _, f := range fits {
+			if f.copies > 0 {
+				names = append(names, f.node.Name)
+			}
+		}
+		fo.printNames(names)
+		return nil
+	}
+
+	_, ephemeral := requests[v1.ResourceEphemeralStorage]
+	ephemeral = ephemeral || fo.ephemeralStorage
+
+	if !fo.noHeaders {
+		fo.table.Header = fo.fitTableHeader(ephemeral)
+	}
+
+	total, hosts := int64(0), 0
+	for _, f := range fits {
+		fo.table.AddRow(fo.fitTableRow(f, ephemeral))
+		if f.copies > 0 {
+			total += f.copies
+			hosts++
+		}
+	}
+
+	fo.table.Print()
+
+	if fo.noHeaders {
+		return nil
+	}
+
+	if total >= fo.replicas {
+		fmt.Fprintf(fo.table.Output, "\n%d replicas fit (up to %d copies on %d nodes)\n", fo.replicas, total, hosts)
+	} else {
+		fmt.Fprintf(fo.table.Output, "\n%d replicas do not fit (only %d copies on %d nodes)\n", fo.replicas, total, hosts)
+	}
+
+	return nil
+}
+
+// getFitNodes returns copies of the pod each node can host and reasons of nodes which can not host it
+// items are resources of nodes in the same order as nodes.
+// Nodes which can host more copies come first.
+func getFitNodes(nodes []v1.Node, items []types.Node, pod v1.Pod, requests map[v1.ResourceName]int64) []fitNode {
+
+	fits := []fitNode{}
+
+	for i, item := range items {
+		n := placement.Node{Node: nodes[i], Free: freeResources(item)}
+
+		f := fitNode{
+			node:    item,
+			reasons: n.Reasons(pod, requests),
+		}
+		if len(f.reasons) == 0 {
+			f.copies = placement.Copies(n.Free, requests)
+		}
+
+		fits = append(fits, f)
+	}
+
+	sort.SliceStable(fits, func(i, j int) bool {
+		return fits[i].copies > fits[j].copies
+	})
+
+	return fits
+}
+
+// freeResources returns free resources (allocatable - requested) of a node with free slots of pods
+func freeResources(n types.Node) map[v1.ResourceName]int64 {
+
+	free := map[v1.ResourceName]int64{
+		v1.ResourceCPU:              n.CPU.Free,
+		v1.ResourceMemory:           n.Memory.Free,
+		v1.ResourceEphemeralStorage: n.EphemeralStorage.Free,
+		v1.ResourcePods:             n.PodsAllocatable - n.Pods,
+	}
+
+	for name, r := range n.Resources {
+		free[v1.ResourceName(name)] = r.Free
+	}
+
+	return free
+}
+
+// extendedResourceNames returns sorted names of extended resources in requests
+func extendedResourceNames(requests map[v1.ResourceName]int64) []string {
+
+	names := []string{}
+	for name := range requests {
+		if !util.IsStandardResource(name) {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// fitTableHeader returns table headers of fit
+func (fo *fitOptions) fitTableHeader(ephemeral bool) []string {
+
+	hStatus := "STATUS"
+	hCPUFree := "CPU/free"
+	hMEMFree := "MEM/free"
+	hEPHFree := "EPH/free"
+
+	if !fo.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hStatus)  // STATUS
+		util.DefaultColor(&hCPUFree) // CPU/free
+		util.DefaultColor(&hMEMFree) // MEM/free
+		util.DefaultColor(&hEPHFree) // EPH/free
+	}
+
+	header := []string{"NAME", hStatus, hCPUFree, hMEMFree}
+
+	if ephemeral {
+		header = append(header, hEPHFree)
+	}
+
+	for _, name := range fo.resourceNames {
+		h := name + "/free"
+		if !fo.nocolor {
+			util.DefaultColor(&h)
+		}
+		header = append(header, h)
+	}
+
+	return append(header, "PODS/free", "FITS", "REASON")
+}
+
+// fitTableRow returns table row of a node
+func (fo *fitOptions) fitTableRow(f fitNode, ephemeral bool) []string {
+
+	n := f.node
+
+	status := n.Status
+	if fo.emojiStatus {
+		status = util.GetNodeStatusEmoji(status)
+	}
+	util.SetNodeStatusColor(&status, fo.nocolor)
+
+	row := []string{
+		n.Name,
+		status,
+		fo.toColorFree(fo.toMilliUnit(n.CPU.Free), n.CPU.Free),
+		fo.toColorFree(fo.toUnit(n.Memory.Free), n.Memory.Free),
+	}
+
+	if ephemeral {
+		row = append(row, fo.toColorFree(fo.toUnit(n.EphemeralStorage.Free), n.EphemeralStorage.Free))
+	}
+
+	for _, name := range fo.resourceNames {
+		free := n.Resources[name].Free
+		row = append(row, fo.toColorFree(fo.toResourceUnit(name, free), free))
+	}
+
+	reason := "-"
+	if len(f.reasons) > 0 {
+		reason = strings.Join(f.reasons, ", ")
+	}
+
+	return append(
+		row,
+		strconv.FormatInt(n.PodsAllocatable-n.Pods, 10),
+		strconv.FormatInt(f.copies, 10),
+		reason,
+	)
+}
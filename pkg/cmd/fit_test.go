@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFitValidate(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		cpu         string
+		memory      string
+		replicas    int64
+		filename    string
+		output      string
+		expected    string
+	}{
+		{"cpu and memory", "500m", "1Gi", 1, "", "", ""},
+		{"cpu only", "2", "", 3, "", "", ""},
+		{"from file", "", "", 1, "pod.yaml", "", ""},
+		{"no pod", "", "", 1, "", "", "--cpu, --memory or --from-file is required"},
+		{"both", "1", "", 1, "pod.yaml", "", "--from-file can not be used with --cpu and --memory"},
+		{"invalid quantity", "", "8GB", 1, "", "", "invalid --memory: quantities must match the regular expression"},
+		{"no replicas", "1", "", 0, "", "", "--replicas must be greater than 0 (got: 0)"},
+		{"output", "1", "", 1, "", "json", "fit supports only table output (got: json)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			fo := &fitOptions{
+				FreeOptions: &FreeOptions{output: test.output},
+				cpu:         test.cpu,
+				memory:      test.memory,
+				replicas:    test.replicas,
+				filename:    test.filename,
+			}
+
+			err := fo.Validate()
+			if test.expected == "" {
+				if err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+				}
+				return
+			}
+
+			if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+				return
+			}
+		})
+	}
+}
+
+func TestReadPod(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		manifest    string
+		expected    string
+	}{
+		{
+			"pod",
+			`apiVersion: v1
+kind: Pod
+metadata:
+  name: big
+spec:
+  nodeSelector:
+    pool: highmem
+  tolerations:
+  - key: dedicated
+    operator: Exists
+  initContainers:
+  - name: proxy
+    restartPolicy: Always
+  containers:
+  - name: app
+    resources:
+      requests:
+        cpu: "2"
+        memory: 8Gi
+`,
+			"",
+		},
+		{
+			"not a pod",
+			`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "big"}}`,
+			"is not a Pod (kind: Deployment)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			f, err := ioutil.TempFile("", "pod")
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			defer os.Remove(f.Name())
+
+			if _, err := f.WriteString(test.manifest); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			f.Close()
+
+			pod, err := readPod(f.Name())
+			if test.expected != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), test.expected) {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if pod.Spec.NodeSelector["pool"] != "highmem" || len(pod.Spec.Tolerations) != 1 {
+				t.Errorf("[%s] unexpected pod spec: %v", test.description, pod.Spec)
+				return
+			}
+
+			if !util.IsRestartableInitContainer(pod, "proxy") {
+				t.Errorf("[%s] expected sidecar(proxy) differ (got: %v)", test.description, pod.ObjectMeta.Annotations)
+				return
+			}
+
+			requests := pod.Spec.Containers[0].Resources.Requests
+			if requests.Cpu().MilliValue() != 2000 || requests.Memory().Value() != 8*1024*1024*1024 {
+				t.Errorf("[%s] unexpected requests: %v", test.description, requests)
+				return
+			}
+		})
+	}
+
+	t.Run("no file", func(t *testing.T) {
+		if _, err := readPod("/no/such/pod.yaml"); err == nil {
+			t.Errorf("unexpected error: should return error")
+			return
+		}
+	})
+}
+
+func TestShowFit(t *testing.T) {
+
+	var tests = []struct {
+		description  string
+		replicas     int64
+		antiAffinity bool
+		namesOnly    bool
+		expected     []string
+	}{
+		{
+			"fits",
+			1,
+			false,
+			false,
+			[]string{
+				"NAME    STATUS     CPU/free   MEM/free   PODS/free   FITS   REASON",
+				"node1   Ready      2500m      2K         108         2      -",
+				"node2   NotReady   8          8K         110         0      not ready",
+				"",
+				"1 replicas fit (up to 2 copies on 1 nodes)",
+				"",
+			},
+		},
+		{
+			"does not fit",
+			3,
+			false,
+			false,
+			[]string{
+				"NAME    STATUS     CPU/free   MEM/free   PODS/free   FITS   REASON",
+				"node1   Ready      2500m      2K         108         2      -",
+				"node2   NotReady   8          8K         110         0      not ready",
+				"",
+				"3 replicas do not fit (only 2 copies on 1 nodes)",
+				"",
+			},
+		},
+		{
+			"one copy per node",
+			2,
+			true,
+			false,
+			[]string{
+				"NAME    STATUS     CPU/free   MEM/free   PODS/free   FITS   REASON",
+				"node1   Ready      2500m      2K         108         1      -",
+				"node2   NotReady   8          8K         110         0      not ready",
+				"",
+				"2 replicas do not fit (only 1 copies on 1 nodes)",
+				"",
+			},
+		},
+		{
+			"names only",
+			1,
+			false,
+			true,
+			[]string{"node1", ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(&testPods[0], &testPods[1])
+
+			buffer := &bytes.Buffer{}
+			fo := &fitOptions{
+				FreeOptions: &FreeOptions{
+					table:     table.NewOutputTable(buffer),
+					kByte:     true,
+					nocolor:   true,
+					noMetrics: true,
+					namesOnly: test.namesOnly,
+					podClient: fakePodClient.CoreV1().Pods("default"),
+				},
+				cpu:      "1",
+				memory:   "1k",
+				replicas: test.replicas,
+			}
+
+			pod, err := fo.pod()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if test.antiAffinity {
+				pod.ObjectMeta.Labels = map[string]string{"app": "fit"}
+				pod.Spec.Affinity = &v1.Affinity{
+					PodAntiAffinity: &v1.PodAntiAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+							{
+								LabelSelector: &metav1.LabelSelector{MatchLabels: pod.ObjectMeta.Labels},
+								TopologyKey:   "kubernetes.io/hostname",
+							},
+						},
+					},
+				}
+			}
+
+			if err := fo.showFit([]v1.Node{testNodes[1], testNodes[0]}, pod); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := strings.Split(buffer.String(), "\n")
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
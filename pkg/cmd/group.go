@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+)
+
+const (
+	// groupNone is label value for nodes without the label
+	groupNone = "<none>"
+)
+
+// getNodeGroups aggregates nodes into one row per label values of keys
+// Name of a group is comma separated label values and Status is "ready/total" nodes
+func getNodeGroups(items []types.Node, keys []string) []types.Node {
+
+	groups := map[string]*types.Node{}
+	ready := map[string]int{}
+	total := map[string]int{}
+
+	for _, item := range items {
+
+		// label values of the node
+		labels := map[string]string{}
+		values := []string{}
+		for _, k := range keys {
+			v, ok := item.Labels[k]
+			if !ok {
+				v = groupNone
+			}
+			labels[k] = v
+			values = append(values, v)
+		}
+		name := strings.Join(values, ",")
+
+		g, ok := groups[name]
+		if !ok {
+			g = &types.Node{Name: name, Labels: labels}
+			groups[name] = g
+		}
+
+		sumNodeResource(&g.CPU, item.CPU)
+		sumNodeResource(&g.Memory, item.Memory)
+		sumNodeResource(&g.EphemeralStorage, item.EphemeralStorage)
+		g.Pods += item.Pods
+		g.PodsAllocatable += item.PodsAllocatable
+		g.TerminatingPods += item.TerminatingPods
+		g.Containers += item.Containers
+		g.Resources = sumResources(g.Resources, item.Resources)
+
+		total[name]++
+		if item.Status == "Ready" {
+			ready[name]++
+		}
+	}
+
+	names := []string{}
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []types.Node{}
+	for _, name := range names {
+		g := groups[name]
+		g.Status = fmt.Sprintf("%d/%d", ready[name], total[name])
+		setNodeResourcePercentage(&g.CPU)
+		setNodeResourcePercentage(&g.Memory)
+		setNodeResourcePercentage(&g.EphemeralStorage)
+		setResourcesPercentage(g.Resources)
+		result = append(result, *g)
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetNodeGroups(t *testing.T) {
+
+	items := []types.Node{
+		{
+			Name:   "node1",
+			Status: "Ready",
+			Labels: map[string]string{"zone": "a", "pool": "x"},
+			CPU:    types.NodeResource{Requested: 1000, Allocatable: 4000},
+			Pods:   1,
+		},
+		{
+			Name:   "node2",
+			Status: "NotReady",
+			Labels: map[string]string{"zone": "a", "pool": "x"},
+			CPU:    types.NodeResource{Requested: 3000, Allocatable: 4000},
+			Pods:   2,
+		},
+		{
+			Name:   "node3",
+			Status: "Ready",
+			Labels: map[string]string{"zone": "b"},
+			CPU:    types.NodeResource{Requested: 500, Allocatable: 2000},
+			Pods:   3,
+		},
+	}
+
+	var tests = []struct {
+		description string
+		keys        []string
+		expected    []types.Node
+	}{
+		{
+			"group by zone",
+			[]string{"zone"},
+			[]types.Node{
+				{
+					Name:   "a",
+					Status: "1/2",
+					Labels: map[string]string{"zone": "a"},
+					CPU:    types.NodeResource{Requested: 4000, Allocatable: 8000, RequestedPercent: 50},
+					Pods:   3,
+				},
+				{
+					Name:   "b",
+					Status: "1/1",
+					Labels: map[string]string{"zone": "b"},
+					CPU:    types.NodeResource{Requested: 500, Allocatable: 2000, RequestedPercent: 25},
+					Pods:   3,
+				},
+			},
+		},
+		{
+			"group by zone and pool",
+			[]string{"zone", "pool"},
+			[]types.Node{
+				{
+					Name:   "a,x",
+					Status: "1/2",
+					Labels: map[string]string{"zone": "a", "pool": "x"},
+					CPU:    types.NodeResource{Requested: 4000, Allocatable: 8000, RequestedPercent: 50},
+					Pods:   3,
+				},
+				{
+					Name:   "b,<none>",
+					Status: "1/1",
+					Labels: map[string]string{"zone": "b", "pool": "<none>"},
+					CPU:    types.NodeResource{Requested: 500, Allocatable: 2000, RequestedPercent: 25},
+					Pods:   3,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getNodeGroups(items, test.keys)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestShowFreeGroupBy(t *testing.T) {
+
+	nodes := []v1.Node{
+		*testNodes[0].DeepCopy(),
+		*testNodes[1].DeepCopy(),
+	}
+	nodes[1].ObjectMeta = metav1.ObjectMeta{Name: "node2"}
+
+	expected := []string{
+		"hostname   READY   CPU/req   CPU/lim   CPU/alloc   CPU/req%   CPU/lim%   MEM/req   MEM/lim   MEM/alloc   MEM/req%   MEM/lim%",
+		"<none>     0/1     1         2         8           12%        25%        1K        2K        8K          12%        25%",
+		"node1      1/1     1         2         4           25%        50%        1K        2K        4K          25%        50%",
+		"",
+	}
+
+	// pod1 on node1 and a copy of pod1 on node2
+	pod4 := testPods[0].DeepCopy()
+	pod4.ObjectMeta.Name = "pod4"
+	pod4.Spec.NodeName = "node2"
+	fakePodClient := fake.NewSimpleClientset(&testPods[0], pod4)
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:   true,
+		table:     table.NewOutputTable(buffer),
+		noMetrics: true,
+		groupBy:   []string{"hostname"},
+		podClient: fakePodClient.CoreV1().Pods("default"),
+	}
+	o.prepareFreeTableHeader()
+
+	if err := o.showFree(nodes); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	e := strings.Join(expected, "\n")
+	if buffer.String() != e {
+		t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+		return
+	}
+}
@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/placement"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// headroomLong defines long description of headroom
+	headroomLong = templates.LongDesc(`
+		Show how many more replicas of a workload can be scheduled now and which node pools they come from.
+
+		Requests, tolerations, nodeSelector and required node affinity of the pod template are checked
+		against free resources of nodes (allocatable minus effective requests of pods in all namespaces).
+		Required pod anti-affinity with topology key kubernetes.io/hostname allows one replica per node.
+
+		Node pools are label values of --group-by, or the first well-known node pool label found on the node
+		(e.g. cloud.google.com/gke-nodepool, eks.amazonaws.com/nodegroup, node.kubernetes.io/instance-type).
+	`)
+
+	// headroomExample defines command examples of headroom
+	headroomExample = templates.Examples(`
+		# Show how many more replicas of deployment "api" in namespace "prod" can be scheduled.
+		kubectl free headroom deployment/api -n prod
+
+		# Show headroom of a statefulset per zone on nodes labeled as workers.
+		kubectl free headroom statefulset/db -n prod -l node-role=worker --group-by topology.kubernetes.io/zone
+	`)
+)
+
+// nodePoolLabels are labels of node pools, the first one found on a node is used
+var nodePoolLabels = []string{
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+	"karpenter.sh/nodepool",
+	"node.kubernetes.io/instance-type",
+}
+
+// headroomOptions is options of headroom subcommand
+type headroomOptions struct {
+	*FreeOptions
+
+	// namespace of the workload
+	namespace string
+}
+
+// workload has a pod template and replicas of a workload
+type workload struct {
+	kind     string
+	name     string
+	replicas int32
+	selector *metav1.LabelSelector
+	template v1.PodTemplateSpec
+}
+
+// headroomPool is headroom of nodes in a node pool
+type headroomPool struct {
+	name string
+
+	// nodes is number of nodes and fits is number of nodes which can host a replica
+	nodes int
+	fits  int
+
+	replicas int64
+	headroom int64
+
+	// reasons why nodes can not host a replica with number of the nodes
+	reasons map[string]int
+}
+
+// NewCmdHeadroom is a cobra command of replica headroom of a workload
+func NewCmdHeadroom(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+
+	ho := &headroomOptions{
+		FreeOptions: o,
+		namespace:   v1.NamespaceDefault,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "headroom TYPE/NAME [node...]",
+		Short:   "Show how many more replicas of a workload can be scheduled.",
+		Long:    headroomLong,
+		Example: headroomExample,
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			// free resources are computed from pods in all namespaces
+			o.allNamespaces = true
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(ho.Validate())
+			if *o.configFlags.Namespace != "" {
+				ho.namespace = *o.configFlags.Namespace
+			}
+			cmdutil.CheckErr(ho.Run(args[0], args[1:]))
+		},
+	}
+
+	o.addFlags(cmd.Flags(), "no-headers", "group-by")
+
+	return cmd
+}
+
+// Validate ensures that options are supported by headroom
+func (ho *headroomOptions) Validate() error {
+
+	if ho.output != "" {
+		return fmt.Errorf("headroom supports only table output (got: %s)", ho.output)
+	}
+
+	if ho.namesOnly {
+		return fmt.Errorf("--names-only is not supported with headroom")
+	}
+
+	return nil
+}
+
+// Run shows headroom of the workload on nodes
+func (ho *headroomOptions) Run(ref string, args []string) error {
+
+	// usage is not considered by the scheduler
+	ho.noMetrics = true
+
+	w, err := ho.getWorkload(ref)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := ho.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	pods, err := ho.listPods()
+	if err != nil {
+		return err
+	}
+
+	return ho.showHeadroom(w, nodes, pods)
+}
+
+// getWorkload returns the workload of TYPE/NAME (e.g. deployment/api)
+func (ho *headroomOptions) getWorkload(ref string) (workload, error) {
+
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return workload{}, fmt.Errorf("workload must be TYPE/NAME (e.g. deployment/api): %s", ref)
+	}
+	name := parts[1]
+
+	switch strings.ToLower(parts[0]) {
+	case "deployment", "deployments", "deploy":
+		d, err := ho.appsClient.Deployments(ho.namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return workload{}, err
+		}
+		return newWorkload("Deployment", name, d.Spec.Replicas, d.Spec.Selector, d.Spec.Template), nil
+
+	case "statefulset", "statefulsets", "sts":
+		s, err := ho.appsClient.StatefulSets(ho.namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return workload{}, err
+		}
+		return newWorkload("StatefulSet", name, s.Spec.Replicas, s.Spec.Selector, s.Spec.Template), nil
+
+	case "replicaset", "replicasets", "rs":
+		r, err := ho.appsClient.ReplicaSets(ho.namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return workload{}, err
+		}
+		return newWorkload("ReplicaSet", name, r.Spec.Replicas, r.Spec.Selector, r.Spec.Template), nil
+	}
+
+	return workload{}, fmt.Errorf("unsupported workload type: %s (one of: deployment, statefulset, replicaset)", parts[0])
+}
+
+// newWorkload returns a workload, replicas is 1 if it is not specified as the API defaults
+func newWorkload(kind, name string, replicas *int32, selector *metav1.LabelSelector, template v1.PodTemplateSpec) workload {
+
+	w := workload{
+		kind:     kind,
+		name:     name,
+		replicas: 1,
+		selector: selector,
+		template: template,
+	}
+
+	if replicas != nil {
+		w.replicas = *replicas
+	}
+
+	return w
+}
+
+// showHeadroom prints headroom of the workload per node pool
+func (ho *headroomOptions) showHeadroom(w workload, nodes []v1.Node, pods *v1.PodList) error {
+
+	pod := v1.Pod{ObjectMeta: w.template.ObjectMeta, Spec: w.template.Spec}
+	requests := placement.PodRequests(pod)
+
+	// extended resources requested by the pod are collected as --resources does
+	ho.resourceNames = extendedResourceNames(requests)
+
+	items, err := ho.getNodeResourcesFromPods(nodes, pods)
+	if err != nil {
+		return err
+	}
+
+	fits := getFitNodes(nodes, items, pod, requests)
+
+	selector, err := metav1.LabelSelectorAsSelector(w.selector)
+	if err != nil {
+		return err
+	}
+
+	antiAffinity, err := placement.AntiAffinitySelectors(pod.Spec)
+	if err != nil {
+		return err
+	}
+
+	// replicas of the workload and pods which the pod can not be co-located with per node
+	replicas := map[string]int64{}
+	conflicts := map[string]bool{}
+	for _, p := range pods.Items {
+		if p.ObjectMeta.Namespace != ho.namespace || p.Spec.NodeName == "" || !util.IsCountedPhase(string(p.Status.Phase), ho.countPhases) {
+			continue
+		}
+		if selector.Matches(labels.Set(p.ObjectMeta.Labels)) {
+			replicas[p.Spec.NodeName]++
+		}
+		if placement.MatchesAny(antiAffinity, p.ObjectMeta.Labels) {
+			conflicts[p.Spec.NodeName] = true
+		}
+	}
+
+	// replicas are spread one per node if they repel each other
+	onePerNode := placement.MatchesAny(antiAffinity, pod.ObjectMeta.Labels)
+
+	for i := range fits {
+		f := &fits[i]
+		if conflicts[f.node.Name] {
+			f.copies = 0
+			f.reasons = append(f.reasons, "pod anti-affinity")
+		} else if onePerNode && f.copies > 1 {
+			f.copies = 1
+		}
+	}
+
+	pools := getHeadroomPools(fits, replicas, ho.groupBy)
+
+	if !ho.noHeaders {
+		hPool := "POOL"
+		if len(ho.groupBy) > 0 {
+			hPool = strings.Join(ho.groupBy, ",")
+		}
+		ho.table.Header = []string{hPool, "NODES", "NODES/fit", "REPLICAS", "HEADROOM", "REASON"}
+	}
+
+	headroom := int64(0)
+	for _, p := range pools {
+		ho.table.AddRow(headroomTableRow(p))
+		headroom += p.headroom
+	}
+
+	ho.table.Print()
+
+	if !ho.noHeaders {
+		fmt.Fprintf(ho.table.Output, "\n%s/%s: %d replicas, %d more can be scheduled\n", strings.ToLower(w.kind), w.name, w.replicas, headroom)
+	}
+
+	return nil
+}
+
+// getHeadroomPools sums copies of nodes per node pool
+// keys are labels of node pools (--group-by option), well-known labels are used if empty.
+// Pools which have more headroom come first.
+func getHeadroomPools(fits []fitNode, replicas map[string]int64, keys []string) []headroomPool {
+
+	pools := map[string]*headroomPool{}
+
+	for _, f := range fits {
+		name := getNodePool(f.node.Labels, keys)
+
+		p, ok := pools[name]
+		if !ok {
+			p = &headroomPool{name: name, reasons: map[string]int{}}
+			pools[name] = p
+		}
+
+		p.nodes++
+		p.replicas += replicas[f.node.Name]
+		p.headroom += f.copies
+
+		if f.copies > 0 {
+			p.fits++
+			continue
+		}
+		for _, reason := range f.reasons {
+			p.reasons[reason]++
+		}
+	}
+
+	result := []headroomPool{}
+	for _, p := range pools {
+		result = append(result, *p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].headroom != result[j].headroom {
+			return result[i].headroom > result[j].headroom
+		}
+		return result[i].name < result[j].name
+	})
+
+	return result
+}
+
+// getNodePool returns comma separated label values of keys, or the first well-known node pool label
+func getNodePool(nodeLabels map[string]string, keys []string) string {
+
+	if len(keys) > 0 {
+		values := []string{}
+		for _, k := range keys {
+			v, ok := nodeLabels[k]
+			if !ok {
+				v = groupNone
+			}
+			values = append(values, v)
+		}
+		return strings.Join(values, ",")
+	}
+
+	for _, k := range nodePoolLabels {
+		if v, ok := nodeLabels[k]; ok {
+			return v
+		}
+	}
+
+	return groupNone
+}
+
+// headroomTableRow returns table row of a node pool
+func headroomTableRow(p headroomPool) []string {
+	return []string{
+		p.name,
+		strconv.Itoa(p.nodes),
+		strconv.Itoa(p.fits),
+		strconv.FormatInt(p.replicas, 10),
+		strconv.FormatInt(p.headroom, 10),
+		formatReasons(p.reasons),
+	}
+}
+
+// formatReasons returns reasons with number of nodes sorted by the number (e.g. "insufficient cpu (3), not ready (1)")
+// "-" is returned if there is no reason.
+func formatReasons(counts map[string]int) string {
+
+	reasons := []string{}
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+
+	if len(reasons) == 0 {
+		return "-"
+	}
+
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	for i, r := range reasons {
+		reasons[i] = fmt.Sprintf("%s (%d)", r, counts[r])
+	}
+
+	return strings.Join(reasons, ", ")
+}
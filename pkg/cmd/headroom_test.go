@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+// testPodTemplate returns a pod template of app=api requesting 1 cpu and 500 bytes memory
+func testPodTemplate(antiAffinity bool) v1.PodTemplateSpec {
+
+	template := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"app": "api"},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: "api",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(500, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if antiAffinity {
+		template.Spec.Affinity = &v1.Affinity{
+			PodAntiAffinity: &v1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				},
+			},
+		}
+	}
+
+	return template
+}
+
+func TestGetWorkload(t *testing.T) {
+
+	replicas := int32(3)
+	fakeAppsClient := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Template: testPodTemplate(false)},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		},
+	)
+
+	var tests = []struct {
+		description string
+		ref         string
+		kind        string
+		replicas    int32
+		expected    string
+	}{
+		{"deployment", "deployment/api", "Deployment", 3, ""},
+		{"short name", "deploy/api", "Deployment", 3, ""},
+		{"default replicas", "sts/db", "StatefulSet", 1, ""},
+		{"not found", "replicaset/api", "", 0, `replicasets.apps "api" not found`},
+		{"unsupported type", "daemonset/agent", "", 0, "unsupported workload type: daemonset (one of: deployment, statefulset, replicaset)"},
+		{"no type", "api", "", 0, "workload must be TYPE/NAME (e.g. deployment/api): api"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ho := &headroomOptions{
+				FreeOptions: &FreeOptions{appsClient: fakeAppsClient.AppsV1()},
+				namespace:   "default",
+			}
+
+			w, err := ho.getWorkload(test.ref)
+			if test.expected != "" {
+				if err == nil || err.Error() != test.expected {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if w.kind != test.kind || w.replicas != test.replicas {
+				t.Errorf("[%s] expected(%s, %d) differ (got: %s, %d)", test.description, test.kind, test.replicas, w.kind, w.replicas)
+				return
+			}
+		})
+	}
+}
+
+func TestShowHeadroom(t *testing.T) {
+
+	node1 := testNodes[0].DeepCopy()
+	node1.ObjectMeta.Labels = map[string]string{"cloud.google.com/gke-nodepool": "pool-a"}
+	node2 := testNodes[1].DeepCopy()
+	node2.ObjectMeta.Labels = map[string]string{"cloud.google.com/gke-nodepool": "pool-b"}
+
+	// a replica running on node1
+	replica := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "default", Labels: map[string]string{"app": "api"}},
+		Spec:       testPodTemplate(true).Spec,
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	replica.Spec.NodeName = "node1"
+
+	var tests = []struct {
+		description  string
+		antiAffinity bool
+		pods         []runtime.Object
+		expected     []string
+	}{
+		{
+			"headroom",
+			false,
+			[]runtime.Object{&testPods[0], &testPods[1]},
+			[]string{
+				"POOL     NODES   NODES/fit   REPLICAS   HEADROOM   REASON",
+				"pool-a   1       1           0          2          -",
+				"pool-b   1       0           0          0          not ready (1)",
+				"",
+				"deployment/api: 2 replicas, 2 more can be scheduled",
+				"",
+			},
+		},
+		{
+			"one per node",
+			true,
+			[]runtime.Object{&testPods[0], &testPods[1]},
+			[]string{
+				"POOL     NODES   NODES/fit   REPLICAS   HEADROOM   REASON",
+				"pool-a   1       1           0          1          -",
+				"pool-b   1       0           0          0          not ready (1)",
+				"",
+				"deployment/api: 2 replicas, 1 more can be scheduled",
+				"",
+			},
+		},
+		{
+			"anti-affinity with a replica",
+			true,
+			[]runtime.Object{&testPods[0], &testPods[1], replica},
+			[]string{
+				"POOL     NODES   NODES/fit   REPLICAS   HEADROOM   REASON",
+				"pool-a   1       0           1          0          pod anti-affinity (1)",
+				"pool-b   1       0           0          0          not ready (1)",
+				"",
+				"deployment/api: 2 replicas, 0 more can be scheduled",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(test.pods...)
+
+			buffer := &bytes.Buffer{}
+			ho := &headroomOptions{
+				FreeOptions: &FreeOptions{
+					table:     table.NewOutputTable(buffer),
+					nocolor:   true,
+					noMetrics: true,
+					podClient: fakePodClient.CoreV1().Pods(""),
+				},
+				namespace: "default",
+			}
+
+			w := workload{
+				kind:     "Deployment",
+				name:     "api",
+				replicas: 2,
+				selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				template: testPodTemplate(test.antiAffinity),
+			}
+
+			pods, err := ho.listPods()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if err := ho.showHeadroom(w, []v1.Node{*node1, *node2}, pods); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			// pods are listed once
+			if calls := countListPods(fakePodClient); calls != 1 {
+				t.Errorf("[%s] expected(1) list calls differ (got: %d)", test.description, calls)
+				return
+			}
+
+			actual := strings.Split(buffer.String(), "\n")
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestGetNodePool(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		labels      map[string]string
+		keys        []string
+		expected    string
+	}{
+		{"gke", map[string]string{"cloud.google.com/gke-nodepool": "default-pool"}, []string{}, "default-pool"},
+		{"instance type", map[string]string{"node.kubernetes.io/instance-type": "m5.large"}, []string{}, "m5.large"},
+		{"no pool", map[string]string{}, []string{}, "<none>"},
+		{"group by", map[string]string{"zone": "a", "pool": "gpu"}, []string{"zone", "pool", "arch"}, "a,gpu,<none>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getNodePool(test.labels, test.keys)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/get"
+)
+
+const (
+	outputCSV = "csv"
+	outputTSV = "tsv"
+)
+
+// printObject prints structured document with -o format
+// json, yaml, go-template, jsonpath and custom-columns are supported
+func (o *FreeOptions) printObject(obj runtime.Object) error {
+
+	printer, err := genericclioptions.NewJSONYamlPrintFlags().ToPrinter(o.output)
+
+	// -o go-template=... and -o jsonpath=...
+	if genericclioptions.IsNoCompatiblePrinterError(err) {
+		printer, err = genericclioptions.NewKubeTemplatePrintFlags().ToPrinter(o.output)
+	}
+
+	// -o custom-columns=...
+	if genericclioptions.IsNoCompatiblePrinterError(err) {
+		printer, err = (&get.CustomColumnsPrintFlags{NoHeaders: o.noHeaders}).ToPrinter(o.output)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return printer.PrintObj(obj, o.Out)
+}
+
+// printRecords prints table rows as csv/tsv
+func (o *FreeOptions) printRecords() error {
+
+	if o.output == outputTSV {
+		return o.table.PrintTSV()
+	}
+
+	return o.table.PrintCSV()
+}
+
+// printWarning prints a warning message to stderr
+// Warnings are not mixed into stdout so that structured outputs stay parsable
+func (o *FreeOptions) printWarning(format string, a ...interface{}) {
+	if o.ErrOut == nil {
+		return
+	}
+	fmt.Fprintf(o.ErrOut, "warning: "+format+"\n", a...)
+}
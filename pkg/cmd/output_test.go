@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestPrintObject(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		output      string
+		expected    string
+		expectedErr bool
+	}{
+		{"json", "json", "{\n    \"kind\": \"NodeList\",\n    \"apiVersion\": \"kubectl-free/v1alpha1\",\n    \"items\": []\n}\n", false},
+		{"yaml", "yaml", "apiVersion: kubectl-free/v1alpha1\nitems: []\nkind: NodeList\n", false},
+		{"unknown", "wide", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				IOStreams: genericclioptions.IOStreams{Out: buffer},
+				output:    test.output,
+			}
+
+			err := o.printObject(types.NewNodeList([]types.Node{}))
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("[%s] unexpected error: should return err", test.description)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if buffer.String() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestPrintObjectTemplate(t *testing.T) {
+
+	list := types.NewNodeList([]types.Node{
+		{Name: "node1", CPU: types.NodeResource{Requested: 1000}},
+		{Name: "node2", CPU: types.NodeResource{Requested: 500}},
+	})
+
+	var tests = []struct {
+		description string
+		output      string
+		noHeaders   bool
+		expected    string
+	}{
+		{"custom-columns", "custom-columns=NAME:.name,CPU:.cpu.requested", false, "NAME    CPU\nnode1   1000\nnode2   500\n"},
+		{"custom-columns without headers", "custom-columns=NAME:.name,CPU:.cpu.requested", true, "node1   1000\nnode2   500\n"},
+		{"jsonpath", "jsonpath={.items[*].name}", false, "node1 node2"},
+		{"go-template", "go-template={{range .items}}{{.name}} {{end}}", false, "node1 node2 "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				IOStreams: genericclioptions.IOStreams{Out: buffer},
+				output:    test.output,
+				noHeaders: test.noHeaders,
+			}
+
+			if err := o.printObject(list); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if buffer.String() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestPrintWarning(t *testing.T) {
+
+	t.Run("to stderr", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		o := &FreeOptions{
+			IOStreams: genericclioptions.IOStreams{Out: out, ErrOut: errOut},
+		}
+
+		o.printWarning("no metrics sample for nodes: %s", "node1")
+
+		expected := "warning: no metrics sample for nodes: node1\n"
+		if errOut.String() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, errOut.String())
+			return
+		}
+
+		if out.Len() != 0 {
+			t.Errorf("expected empty stdout (got: %s)", out.String())
+			return
+		}
+	})
+
+	t.Run("without stderr", func(t *testing.T) {
+		o := &FreeOptions{}
+		o.printWarning("ignored")
+	})
+}
@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/placement"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	// pendingLong defines long description of pending
+	pendingLong = templates.LongDesc(`
+		Show unscheduled pods with the best candidate node and which resources are short on the node.
+
+		Requests, tolerations, nodeSelector, required node affinity and required pod anti-affinity of pods are checked
+		against free resources of nodes (allocatable minus effective requests of pods in all namespaces).
+		The best candidate node has the fewest scheduling constraints unmatched, then the fewest resources short.
+		Scheduler events are not read, so volumes, host ports and preemption are not considered.
+	`)
+
+	// pendingExample defines command examples of pending
+	pendingExample = templates.Examples(`
+		# Show unscheduled pods in namespace "prod" and what they are waiting for.
+		kubectl free pending -n prod
+
+		# Show unscheduled pods in all namespaces against nodes labeled as workers.
+		kubectl free pending --all-namespaces -l node-role=worker
+	`)
+)
+
+// pendingOptions is options of pending subcommand
+type pendingOptions struct {
+	*FreeOptions
+
+	// namespace of unscheduled pods, all namespaces if empty
+	namespace string
+}
+
+// pendingPod is an unscheduled pod with the best candidate node
+type pendingPod struct {
+	pod      v1.Pod
+	requests map[v1.ResourceName]int64
+
+	// node is the best candidate node, empty if there is no node
+	node string
+
+	// short is resources short on the node and constraints are other reasons of the node
+	short       map[v1.ResourceName]int64
+	constraints []string
+}
+
+// NewCmdPending is a cobra command of unscheduled pods diagnosis
+func NewCmdPending(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+
+	po := &pendingOptions{
+		FreeOptions: o,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "pending [node...]",
+		Short:   "Show unscheduled pods with the best candidate node and resources short on it.",
+		Long:    pendingLong,
+		Example: pendingExample,
+		Run: func(c *cobra.Command, args []string) {
+			// unscheduled pods are shown in the selected namespaces
+			if !o.allNamespaces {
+				po.namespace = v1.NamespaceDefault
+				if *o.configFlags.Namespace != "" {
+					po.namespace = *o.configFlags.Namespace
+				}
+			}
+			// free resources are computed from pods in all namespaces
+			o.allNamespaces = true
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(po.Validate())
+			cmdutil.CheckErr(po.Run(args))
+		},
+	}
+
+	o.addFlags(cmd.Flags(), "no-headers", "names-only")
+
+	return cmd
+}
+
+// Validate ensures that options are supported by pending
+func (po *pendingOptions) Validate() error {
+
+	if po.output != "" {
+		return fmt.Errorf("pending supports only table output (got: %s)", po.output)
+	}
+
+	return nil
+}
+
+// Run shows unscheduled pods against nodes
+func (po *pendingOptions) Run(args []string) error {
+
+	// usage is not considered by the scheduler
+	po.noMetrics = true
+
+	nodes, err := po.getNodes(args)
+	if err != nil {
+		return err
+	}
+
+	pods, err := po.listPods()
+	if err != nil {
+		return err
+	}
+
+	return po.showPending(nodes, pods)
+}
+
+// showPending prints unscheduled pods with the best candidate node
+func (po *pendingOptions) showPending(nodes []v1.Node, pods *v1.PodList) error {
+
+	unscheduled := []v1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" || pod.Status.Phase != v1.PodPending {
+			continue
+		}
+		if po.namespace != "" && pod.ObjectMeta.Namespace != po.namespace {
+			continue
+		}
+		unscheduled = append(unscheduled, pod)
+	}
+
+	sort.Slice(unscheduled, func(i, j int) bool {
+		if unscheduled[i].ObjectMeta.Namespace != unscheduled[j].ObjectMeta.Namespace {
+			return unscheduled[i].ObjectMeta.Namespace < unscheduled[j].ObjectMeta.Namespace
+		}
+		return unscheduled[i].ObjectMeta.Name < unscheduled[j].ObjectMeta.Name
+	})
+
+	// print namespace/name of unscheduled pods (--names-only option)
+	if po.namesOnly {
+		names := []string{}
+		for _, pod := range unscheduled {
+			names = append(names, pod.ObjectMeta.Namespace+"/"+pod.ObjectMeta.Name)
+		}
+		po.printNames(names)
+		return nil
+	}
+
+	// extended resources requested by the pods are collected as --resources does
+	requests := map[v1.ResourceName]int64{}
+	for _, pod := range unscheduled {
+		for name, v := range placement.PodRequests(pod) {
+			requests[name] += v
+		}
+	}
+	po.resourceNames = extendedResourceNames(requests)
+
+	items, err := po.getNodeResourcesFromPods(nodes, pods)
+	if err != nil {
+		return err
+	}
+
+	counted := util.FilterPods(*pods, po.countPhases)
+	podsByNode := util.GetPodsByNode(&counted)
+
+	candidates := []placement.Node{}
+	for i, item := range items {
+		candidates = append(candidates, placement.Node{
+			Node: nodes[i],
+			Free: freeResources(item),
+			Pods: podsByNode[item.Name].Items,
+		})
+	}
+
+	if !po.noHeaders {
+		po.table.Header = []string{"NAMESPACE", "POD", "CPU/req", "MEM/req", "NODE", "SHORT", "REASON"}
+	}
+
+	fits, short, constrained := 0, 0, 0
+	for _, pod := range unscheduled {
+		p := getPendingPod(pod, candidates)
+		po.table.AddRow(po.pendingTableRow(p))
+
+		switch {
+		case p.node == "" || len(p.constraints) > 0:
+			constrained++
+		case len(p.short) > 0:
+			short++
+		default:
+			fits++
+		}
+	}
+
+	po.table.Print()
+
+	if !po.noHeaders {
+		fmt.Fprintf(po.table.Output, "\n%d pods pending: %d short of resources, %d blocked by constraints, %d fit now\n", len(unscheduled), short, constrained, fits)
+	}
+
+	return nil
+}
+
+// getPendingPod returns the pod with the best candidate node
+// The best node has the fewest constraints unmatched, then the fewest resources short,
+// then the smallest shortage relative to requests. The first node wins a tie.
+func getPendingPod(pod v1.Pod, nodes []placement.Node) pendingPod {
+
+	p := pendingPod{pod: pod, requests: placement.PodRequests(pod)}
+
+	// shortage in permille of requests summed over resources
+	score := func(short map[v1.ResourceName]int64) int64 {
+		s := int64(0)
+		for name, v := range short {
+			s += v * 1000 / p.requests[name]
+		}
+		return s
+	}
+
+	// better returns true if a node is a better candidate than the current one
+	better := func(constraints []string, short map[v1.ResourceName]int64) bool {
+		if p.node == "" {
+			return true
+		}
+		if len(constraints) != len(p.constraints) {
+			return len(constraints) < len(p.constraints)
+		}
+		if len(short) != len(p.short) {
+			return len(short) < len(p.short)
+		}
+		return score(short) < score(p.short)
+	}
+
+	for _, n := range nodes {
+		constraints := n.Constraints(pod)
+		short := placement.Shortage(n.Free, p.requests)
+
+		if !better(constraints, short) {
+			continue
+		}
+
+		p.node = n.Node.ObjectMeta.Name
+		p.short = short
+		p.constraints = constraints
+	}
+
+	return p
+}
+
+// pendingTableRow returns table row of an unscheduled pod
+func (po *pendingOptions) pendingTableRow(p pendingPod) []string {
+
+	node, short, reason := "-", "-", "no nodes"
+	if p.node != "" {
+		node = p.node
+		short = po.formatShortage(p.short)
+		reason = "-"
+		if len(p.constraints) > 0 {
+			reason = strings.Join(p.constraints, ", ")
+		}
+	}
+
+	return []string{
+		p.pod.ObjectMeta.Namespace,
+		p.pod.ObjectMeta.Name,
+		po.toMilliUnitOrDash(p.requests[v1.ResourceCPU]),
+		po.toUnitOrDash(p.requests[v1.ResourceMemory]),
+		node,
+		short,
+		reason,
+	}
+}
+
+// formatShortage returns resources short with amounts sorted by name (e.g. "cpu 500m, memory 2G")
+// "-" is returned if nothing is short.
+func (po *pendingOptions) formatShortage(short map[v1.ResourceName]int64) string {
+
+	names := []string{}
+	for name := range short {
+		names = append(names, string(name))
+	}
+
+	if len(names) == 0 {
+		return "-"
+	}
+
+	sort.Strings(names)
+
+	for i, name := range names {
+		v := short[v1.ResourceName(name)]
+
+		var amount string
+		switch v1.ResourceName(name) {
+		case v1.ResourceCPU:
+			amount = po.toMilliUnitOrDash(v)
+		case v1.ResourcePods:
+			amount = strconv.FormatInt(v, 10)
+		default:
+			amount = po.toResourceUnitOrDash(name, v)
+		}
+
+		names[i] = name + " " + amount
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowPending(t *testing.T) {
+
+	// testPendingPod returns an unscheduled pod requesting cpu in millicores and 1000 bytes memory
+	testPendingPod := func(name, namespace string, cpu int64) *v1.Pod {
+		pod := testDrainPod(name, cpu, "ReplicaSet", nil)
+		pod.ObjectMeta.Namespace = namespace
+		pod.Spec.NodeName = ""
+		pod.Status.Phase = v1.PodPending
+		return &pod
+	}
+
+	gpu := testPendingPod("gpu", "default", 100)
+	gpu.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = *resource.NewQuantity(1, resource.DecimalSI)
+
+	selector := testPendingPod("selector", "default", 100)
+	selector.Spec.NodeSelector = map[string]string{"pool": "gpu"}
+
+	var tests = []struct {
+		description string
+		namespace   string
+		namesOnly   bool
+		expected    []string
+	}{
+		{
+			"pending",
+			"default",
+			false,
+			[]string{
+				"NAMESPACE   POD        CPU/req   MEM/req   NODE    SHORT              REASON",
+				"default     big        3         1K        node1   cpu 500m           -",
+				"default     gpu        100m      1K        node1   nvidia.com/gpu 1   -",
+				"default     selector   100m      1K        node1   -                  node selector",
+				"default     small      100m      1K        node1   -                  -",
+				"",
+				"4 pods pending: 2 short of resources, 1 blocked by constraints, 1 fit now",
+				"",
+			},
+		},
+		{
+			"names only in all namespaces",
+			"",
+			true,
+			[]string{"default/big", "default/gpu", "default/selector", "default/small", "other/other", ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(
+				&testPods[0],
+				&testPods[1],
+				testPendingPod("big", "default", 3000),
+				testPendingPod("small", "default", 100),
+				testPendingPod("other", "other", 100),
+				gpu,
+				selector,
+			)
+
+			buffer := &bytes.Buffer{}
+			po := &pendingOptions{
+				FreeOptions: &FreeOptions{
+					table:     table.NewOutputTable(buffer),
+					nocolor:   true,
+					noMetrics: true,
+					namesOnly: test.namesOnly,
+					podClient: fakePodClient.CoreV1().Pods(""),
+				},
+				namespace: test.namespace,
+			}
+
+			pods, err := po.listPods()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if err := po.showPending([]v1.Node{testNodes[1], testNodes[0]}, pods); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			// pods are listed once
+			if calls := countListPods(fakePodClient); calls != 1 {
+				t.Errorf("[%s] expected(1) list calls differ (got: %d)", test.description, calls)
+				return
+			}
+
+			actual := strings.Split(buffer.String(), "\n")
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
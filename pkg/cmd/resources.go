@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/collector"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// getResourceNames returns names of extended resources to show (--resources and --all-resources option)
+// --all-resources adds allocatable resources of nodes except ones which have dedicated columns
+func (o *FreeOptions) getResourceNames(nodes []v1.Node) []string {
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range o.resources {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if !o.allResources {
+		return names
+	}
+
+	discovered := []string{}
+	for _, node := range nodes {
+		for name := range node.Status.Allocatable {
+			if util.IsStandardResource(name) || seen[string(name)] {
+				continue
+			}
+			seen[string(name)] = true
+			discovered = append(discovered, string(name))
+		}
+	}
+	sort.Strings(discovered)
+
+	return append(names, discovered...)
+}
+
+// getExtendedResources returns requested and allocatable extended resources of a node
+// a is the aggregate of pods on the node
+func getExtendedResources(node v1.Node, a collector.Aggregate, names []string) map[string]types.NodeResource {
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	resources := map[string]types.NodeResource{}
+	for _, name := range names {
+		requested, limited := a.Requested[v1.ResourceName(name)], a.Limited[v1.ResourceName(name)]
+		allocatable := util.GetResourceValue(node.Status.Allocatable, v1.ResourceName(name))
+
+		r := types.NodeResource{
+			Requested:   requested,
+			Limited:     limited,
+			Allocatable: allocatable,
+			Free:        allocatable - requested,
+		}
+		setNodeResourcePercentage(&r)
+		resources[name] = r
+	}
+
+	return resources
+}
+
+// sumResources adds extended resources of r to sum and returns sum
+// sum is allocated only if r has resources
+func sumResources(sum, r map[string]types.NodeResource) map[string]types.NodeResource {
+
+	if len(r) == 0 {
+		return sum
+	}
+
+	if sum == nil {
+		sum = map[string]types.NodeResource{}
+	}
+
+	for name, v := range r {
+		s := sum[name]
+		sumNodeResource(&s, v)
+		sum[name] = s
+	}
+
+	return sum
+}
+
+// divResources returns extended resources divided by n with recomputed percentages
+// Extended resources have no metrics, so available resources are not averaged.
+func divResources(r map[string]types.NodeResource, n int64) map[string]types.NodeResource {
+
+	if r == nil {
+		return nil
+	}
+
+	d := map[string]types.NodeResource{}
+	for name, v := range r {
+		d[name] = divNodeResource(v, n, n)
+	}
+
+	return d
+}
+
+// setResourcesPercentage computes percentages of extended resources from raw values
+func setResourcesPercentage(r map[string]types.NodeResource) {
+	for name, v := range r {
+		setNodeResourcePercentage(&v)
+		r[name] = v
+	}
+}
+
+// copyResources returns a copy of extended resources
+func copyResources(r map[string]types.NodeResource) map[string]types.NodeResource {
+
+	if r == nil {
+		return nil
+	}
+
+	c := map[string]types.NodeResource{}
+	for name, v := range r {
+		c[name] = v
+	}
+
+	return c
+}
+
+// minMaxResources updates lo and hi with each value of extended resources of r
+func minMaxResources(lo, hi, r map[string]types.NodeResource) {
+	for name, v := range r {
+		l, h := lo[name], hi[name]
+		minMaxNodeResource(&l, &h, v)
+		lo[name], hi[name] = l, h
+	}
+}
+
+// isCountableResource returns false for hugepages which are in bytes
+// Other extended resources (e.g. nvidia.com/gpu) are number of devices
+func isCountableResource(name string) bool {
+	return !strings.HasPrefix(name, v1.ResourceHugePagesPrefix)
+}
+
+// toResourceUnitOrDash returns "-" if "i" is 0, otherwise returns toResourceUnit()
+func (o *FreeOptions) toResourceUnitOrDash(name string, i int64) string {
+
+	if i == 0 {
+		return "-"
+	}
+
+	return o.toResourceUnit(name, i)
+}
+
+// toResourceUnit returns number of devices, or toUnit() for hugepages
+func (o *FreeOptions) toResourceUnit(name string, i int64) string {
+
+	if !isCountableResource(name) {
+		return o.toUnit(i)
+	}
+
+	return strconv.FormatInt(i, 10)
+}
+
+// resourceTableHeader returns headers of extended resources
+func (o *FreeOptions) resourceTableHeader() []string {
+
+	header := []string{}
+
+	for _, name := range o.resourceNames {
+		hReqP := name + "/req%"
+		hLimP := name + "/lim%"
+		if !o.nocolor {
+			// hack: avoid breaking column by escape char
+			util.DefaultColor(&hReqP)
+			util.DefaultColor(&hLimP)
+		}
+		header = append(header, name+"/req", name+"/lim", name+"/alloc", hReqP, hLimP)
+	}
+
+	return header
+}
+
+// resourceTableColumns returns columns of extended resources of a node
+func (o *FreeOptions) resourceTableColumns(n types.Node) []string {
+
+	row := []string{}
+
+	for _, name := range o.resourceNames {
+		r := n.Resources[name]
+		row = append(
+			row,
+			o.toResourceUnitOrDash(name, r.Requested),   // requested
+			o.toResourceUnitOrDash(name, r.Limited),     // limited
+			o.toResourceUnitOrDash(name, r.Allocatable), // allocatable
+			o.toColorPercent(r.RequestedPercent),        // requested %
+			o.toColorPercent(r.LimitedPercent),          // limited %
+		)
+	}
+
+	return row
+}
+
+// resourceRecordHeader returns stable column keys of extended resources for csv/tsv output
+func (o *FreeOptions) resourceRecordHeader() []string {
+
+	header := []string{}
+
+	for _, name := range o.resourceNames {
+		header = append(
+			header,
+			name+"_requested",
+			name+"_limited",
+			name+"_allocatable",
+			name+"_requested_percent",
+			name+"_limited_percent",
+		)
+	}
+
+	return header
+}
+
+// resourceRecord returns raw values of extended resources of a node for csv/tsv output
+func (o *FreeOptions) resourceRecord(n types.Node) []string {
+
+	i := func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	}
+
+	record := []string{}
+
+	for _, name := range o.resourceNames {
+		r := n.Resources[name]
+		record = append(record, i(r.Requested), i(r.Limited), i(r.Allocatable), i(r.RequestedPercent), i(r.LimitedPercent))
+	}
+
+	return record
+}
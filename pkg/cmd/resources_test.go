@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/collector"
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+// prepareTestExtendedResources returns node1 with 4 gpus and 4000 bytes hugepages-2Mi
+// and pod1 which requests 1 gpu and 2000 bytes hugepages-2Mi
+func prepareTestExtendedResources() (*v1.Node, *v1.Pod) {
+
+	node := testNodes[0].DeepCopy()
+	node.Status.Allocatable["nvidia.com/gpu"] = *resource.NewQuantity(4, resource.DecimalSI)
+	node.Status.Allocatable["hugepages-2Mi"] = *resource.NewQuantity(4000, resource.DecimalSI)
+
+	pod := testPods[0].DeepCopy()
+	for _, list := range []v1.ResourceList{pod.Spec.Containers[0].Resources.Requests, pod.Spec.Containers[0].Resources.Limits} {
+		list["nvidia.com/gpu"] = *resource.NewQuantity(1, resource.DecimalSI)
+		list["hugepages-2Mi"] = *resource.NewQuantity(2000, resource.DecimalSI)
+	}
+
+	return node, pod
+}
+
+func TestGetResourceNames(t *testing.T) {
+
+	node, _ := prepareTestExtendedResources()
+
+	var tests = []struct {
+		description  string
+		resources    []string
+		allResources bool
+		expected     []string
+	}{
+		{
+			"no resources",
+			[]string{},
+			false,
+			[]string{},
+		},
+		{
+			"resources",
+			[]string{"nvidia.com/gpu", "nvidia.com/gpu"},
+			false,
+			[]string{"nvidia.com/gpu"},
+		},
+		{
+			"all resources",
+			[]string{},
+			true,
+			[]string{"hugepages-2Mi", "nvidia.com/gpu"},
+		},
+		{
+			"resources first with all resources",
+			[]string{"nvidia.com/gpu"},
+			true,
+			[]string{"nvidia.com/gpu", "hugepages-2Mi"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{
+				resources:    test.resources,
+				allResources: test.allResources,
+			}
+
+			actual := o.getResourceNames([]v1.Node{*node, testNodes[1]})
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestGetExtendedResources(t *testing.T) {
+
+	node, pod := prepareTestExtendedResources()
+	a := collector.NewAggregates(&v1.PodList{Items: []v1.Pod{*pod}}, nil)[node.Name]
+
+	t.Run("gpu", func(t *testing.T) {
+		expected := map[string]types.NodeResource{
+			"nvidia.com/gpu": {
+				Requested:        1,
+				Limited:          1,
+				Allocatable:      4,
+				Free:             3,
+				RequestedPercent: 25,
+				LimitedPercent:   25,
+			},
+		}
+
+		actual := getExtendedResources(*node, a, []string{"nvidia.com/gpu"})
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected(%+v) differ (got: %+v)", expected, actual)
+			return
+		}
+	})
+
+	t.Run("no resources", func(t *testing.T) {
+		if actual := getExtendedResources(*node, a, []string{}); actual != nil {
+			t.Errorf("expected(nil) differ (got: %+v)", actual)
+			return
+		}
+	})
+}
+
+func TestToResourceUnitOrDash(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		name        string
+		value       int64
+		expected    string
+	}{
+		{"no gpu", "nvidia.com/gpu", 0, "-"},
+		{"gpu", "nvidia.com/gpu", 2, "2"},
+		{"large count", "example.com/device", 2000, "2000"},
+		{"hugepages", "hugepages-2Mi", 2000, "2K"},
+		{"no hugepages", "hugepages-1Gi", 0, "-"},
+	}
+
+	o := &FreeOptions{kByte: true}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := o.toResourceUnitOrDash(test.name, test.value)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestShowFreeResources(t *testing.T) {
+
+	node, pod := prepareTestExtendedResources()
+
+	var tests = []struct {
+		description string
+		output      string
+		noheader    bool
+		total       bool
+		expected    []string
+	}{
+		{
+			"table with total",
+			"",
+			true,
+			true,
+			[]string{
+				"node1   Ready   1     2     4     25%   50%   1K    2K    4K    25%   50%   1     1     4     25%   25%   2K    2K    4K    50%   50%",
+				"TOTAL   -       1     2     4     25%   50%   1K    2K    4K    25%   50%   1     1     4     25%   25%   2K    2K    4K    50%   50%",
+				"",
+			},
+		},
+		{
+			"csv",
+			"csv",
+			false,
+			false,
+			[]string{
+				"name,status,cpu_requested_millicores,cpu_limited_millicores,cpu_allocatable_millicores,cpu_requested_percent,cpu_limited_percent,memory_requested_bytes,memory_limited_bytes,memory_allocatable_bytes,memory_requested_percent,memory_limited_percent,nvidia.com/gpu_requested,nvidia.com/gpu_limited,nvidia.com/gpu_allocatable,nvidia.com/gpu_requested_percent,nvidia.com/gpu_limited_percent,hugepages-2Mi_requested,hugepages-2Mi_limited,hugepages-2Mi_allocatable,hugepages-2Mi_requested_percent,hugepages-2Mi_limited_percent",
+				"node1,Ready,1000,2000,4000,25,50,1000,2000,4000,25,50,1,1,4,25,25,2000,2000,4000,50,50",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(pod)
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				output:    test.output,
+				table:     table.NewOutputTable(buffer),
+				kByte:     true,
+				nocolor:   true,
+				noHeaders: test.noheader,
+				noMetrics: true,
+				total:     test.total,
+				resources: []string{"nvidia.com/gpu", "hugepages-2Mi"},
+				podClient: fakePodClient.CoreV1().Pods("default"),
+			}
+
+			if err := o.showFree([]v1.Node{*node}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, e, buffer.String())
+				return
+			}
+		})
+	}
+}
@@ -4,15 +4,22 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/makocchi-git/kubectl-free/pkg/collector"
 	"github.com/makocchi-git/kubectl-free/pkg/table"
 	"github.com/makocchi-git/kubectl-free/pkg/util"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	batchclientv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
 	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	policyclientv1beta1 "k8s.io/client-go/kubernetes/typed/policy/v1beta1"
 	"k8s.io/client-go/rest"
 	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
 	"k8s.io/kubernetes/pkg/kubectl/util/templates"
@@ -40,9 +47,30 @@ var (
 		# Show pod resource usage of Kubernetes nodes with number of pods and containers.
 		kubectl free --pod
 
+		# Show free resources (allocatable - requested) and available resources (allocatable - used).
+		kubectl free --show-free
+
+		# Show cluster total (and average/min/max) of nodes.
+		kubectl free --total
+		kubectl free --summary
+
+		# Show ephemeral-storage columns of nodes and containers.
+		kubectl free --ephemeral-storage
+		kubectl free --list --ephemeral-storage
+
+		# Show extended resources like GPUs and hugepages.
+		kubectl free --resources nvidia.com/gpu,hugepages-2Mi
+		kubectl free --all-resources
+
+		# Count requests of running pods only (default counts all pods except Succeeded/Failed).
+		kubectl free --count-phases Running
+
 		# Using label selector.
 		kubectl free -l key=value
 
+		# Aggregate nodes by label values (e.g. zone and node pool).
+		kubectl free --group-by topology.kubernetes.io/zone --group-by node-pool
+
 		# Print raw(bytes) usage.
 		kubectl free --bytes --without-unit
 
@@ -55,12 +83,61 @@ var (
 		# List resources of containers in pods on nodes with image information.
 		kubectl free --list --list-image
 
+		# Show sum of resources of containers per namespace.
+		kubectl free --by-namespace --all-namespaces
+
+		# Show sum of resources of containers per workload (e.g. Deployment) with replica count.
+		kubectl free --by-owner --all-namespaces
+		kubectl free node1 --by-owner --all-namespaces
+
 		# Print container even if that has no resources/limits.
 		kubectl free --list --list-all
 
+		# Refresh every 10 seconds until Ctrl-C.
+		kubectl free --watch --interval 10s
+
+		# Show nodes sorted by memory requests, the fullest first.
+		kubectl free --sort-by mem.req% --reverse
+		kubectl free --list --sort-by cpu.use --reverse
+
+		# Show nodes whose memory requests exceed 80% or which are not ready, then cordon them.
+		kubectl free --where 'mem.req% > 80 || status != Ready'
+		kubectl free --where 'mem.req% > 80' --names-only | xargs kubectl cordon
+
+		# Show nodes interactively. Press enter to show containers of the node.
+		kubectl free tui
+
+		# Show nodes which can host a pod requesting 2 cpu and 8Gi memory.
+		kubectl free fit --cpu 2 --memory 8Gi
+
+		# Show how many more replicas of a deployment can be scheduled and from which node pools.
+		kubectl free headroom deployment/api -n prod
+
+		# Simulate draining nodes and show where their pods would be re-scheduled.
+		kubectl free drain-sim node1 node2
+
+		# Show a removable set of nodes (greedy) whose pods could be packed onto the other nodes.
+		kubectl free consolidate
+
+		# Show unscheduled pods with the best candidate node and resources short on it.
+		kubectl free pending -n prod
+
 		# Do you like emoji? 😃
 		kubectl free --emoji
 		kubectl free --list --emoji
+
+		# Print raw values as json or yaml.
+		kubectl free -o json
+		kubectl free --list -o yaml
+
+		# Export raw values as csv or tsv.
+		kubectl free -o csv
+		kubectl free --list --list-image -o tsv
+
+		# Print selected fields only.
+		kubectl free -o custom-columns=NAME:.name,MEM/req:.memory.requested
+		kubectl free -o jsonpath='{range .items[*]}{.name}{"\t"}{.cpu.requestedPercent}{"\n"}{end}'
+		kubectl free --list -o go-template='{{range .items}}{{.pod}}/{{.name}}{{"\n"}}{{end}}'
 	`)
 )
 
@@ -77,6 +154,20 @@ type FreeOptions struct {
 	allNamespaces bool
 	noHeaders     bool
 	noMetrics     bool
+	freeColumns   bool
+	total         bool
+	summary       bool
+	groupBy       []string
+	output        string
+	countPhases   []string
+
+	// ephemeral-storage options
+	ephemeralStorage bool
+
+	// extended resource options
+	resources     []string
+	allResources  bool
+	resourceNames []string
 
 	// unit options
 	bytes       bool
@@ -96,15 +187,39 @@ type FreeOptions struct {
 	listContainerImage bool
 	listAll            bool
 
+	// aggregate options
+	byNamespace bool
+	byOwner     bool
+
+	// watch options
+	watch    bool
+	interval time.Duration
+
+	// sort options
+	sortBy  string
+	reverse bool
+
+	// filter options
+	where     string
+	namesOnly bool
+
 	// k8s clients
 	nodeClient        clientv1.NodeInterface
 	podClient         clientv1.PodInterface
+	appsClient        appsclientv1.AppsV1Interface
+	batchClient       batchclientv1.BatchV1Interface
+	policyClient      policyclientv1beta1.PolicyV1beta1Interface
 	metricsPodClient  metricsv1beta1.PodMetricsInterface
 	metricsNodeClient metricsv1beta1.NodeMetricsInterface
 
+	// informer cache of nodes and pods (--watch and tui), resources are listed from the API if nil
+	collector *collector.Collector
+
 	// table headers
-	freeTableHeaders []string
-	listTableHeaders []string
+	freeTableHeaders      []string
+	listTableHeaders      []string
+	namespaceTableHeaders []string
+	ownerTableHeaders     []string
 }
 
 // NewFreeOptions is an instance of FreeOptions
@@ -125,12 +240,29 @@ func NewFreeOptions(streams genericclioptions.IOStreams) *FreeOptions {
 		list:               false,
 		listContainerImage: false,
 		listAll:            false,
+		byNamespace:        false,
+		byOwner:            false,
 		pod:                false,
 		emojiStatus:        false,
 		table:              table.NewOutputTable(os.Stdout),
 		allNamespaces:      false,
 		noHeaders:          false,
 		noMetrics:          false,
+		freeColumns:        false,
+		total:              false,
+		summary:            false,
+		groupBy:            []string{},
+		output:             "",
+		countPhases:        append([]string{}, util.DefaultCountPhases...),
+		ephemeralStorage:   false,
+		resources:          []string{},
+		allResources:       false,
+		watch:              false,
+		interval:           5 * time.Second,
+		sortBy:             "",
+		reverse:            false,
+		where:              "",
+		namesOnly:          false,
 	}
 }
 
@@ -144,6 +276,7 @@ func NewCmdFree(f cmdutil.Factory, streams genericclioptions.IOStreams, version,
 		Long:    freeLong,
 		Example: freeExample,
 		Version: version,
+		Args:    cobra.ArbitraryArgs,
 		Run: func(c *cobra.Command, args []string) {
 			cmdutil.CheckErr(o.Complete(f, c, args))
 			cmdutil.CheckErr(o.Validate())
@@ -151,41 +284,110 @@ func NewCmdFree(f cmdutil.Factory, streams genericclioptions.IOStreams, version,
 		},
 	}
 
-	// bool options
-	cmd.Flags().BoolVarP(&o.bytes, "bytes", "b", o.bytes, `Use 1-byte (1-Byte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.kByte, "kilobytes", "k", o.kByte, `Use 1024-byte (1-Kbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.mByte, "megabytes", "m", o.mByte, `Use 1048576-byte (1-Mbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.gByte, "gigabytes", "g", o.gByte, `Use 1073741824-byte (1-Gbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.binPrefix, "binary-prefix", "B", o.binPrefix, `Use 1024 for basic unit calculation instead of 1000. (print like "KiB")`)
-	cmd.Flags().BoolVarP(&o.withoutUnit, "without-unit", "", o.withoutUnit, `Do not print size with unit string.`)
-	cmd.Flags().BoolVarP(&o.nocolor, "no-color", "", o.nocolor, `Print without ansi color.`)
-	cmd.Flags().BoolVarP(&o.pod, "pod", "p", o.pod, `Show pod count and limit.`)
-	cmd.Flags().BoolVarP(&o.list, "list", "", o.list, `Show container list on node.`)
-	cmd.Flags().BoolVarP(&o.listContainerImage, "list-image", "", o.listContainerImage, `Show pod list on node with container image.`)
-	cmd.Flags().BoolVarP(&o.listAll, "list-all", "", o.listAll, `Show pods even if they have no requests/limit`)
-	cmd.Flags().BoolVarP(&o.emojiStatus, "emoji", "", o.emojiStatus, `Let's smile!! 😃 😭`)
-	cmd.Flags().BoolVarP(&o.allNamespaces, "all-namespaces", "", o.allNamespaces, `If present, list pod resources(limits) across all namespaces. Namespace in current context is ignored even if specified with --namespace.`)
-	cmd.Flags().BoolVarP(&o.noHeaders, "no-headers", "", o.noHeaders, `Do not print table headers.`)
-	cmd.Flags().BoolVarP(&o.noMetrics, "no-metrics", "", o.noMetrics, `Do not print node/pods/containers usage from metrics-server.`)
-
-	// int64 options
-	cmd.Flags().Int64VarP(&o.warnThreshold, "warn-threshold", "", o.warnThreshold, `Threshold of warn(yellow) color for USED column.`)
-	cmd.Flags().Int64VarP(&o.critThreshold, "crit-threshold", "", o.critThreshold, `Threshold of critical(red) color for USED column.`)
-
-	// string option
-	cmd.Flags().StringVarP(&o.labelSelector, "selector", "l", o.labelSelector, `Selector (label query) to filter on.`)
-
-	o.configFlags.AddFlags(cmd.Flags())
+	// options shared by all commands are persistent flags, and other options are added only to commands supporting them
+
+	// unit options
+	cmd.PersistentFlags().BoolVarP(&o.bytes, "bytes", "b", o.bytes, `Use 1-byte (1-Byte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.kByte, "kilobytes", "k", o.kByte, `Use 1024-byte (1-Kbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.mByte, "megabytes", "m", o.mByte, `Use 1048576-byte (1-Mbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.gByte, "gigabytes", "g", o.gByte, `Use 1073741824-byte (1-Gbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.binPrefix, "binary-prefix", "B", o.binPrefix, `Use 1024 for basic unit calculation instead of 1000. (print like "KiB")`)
+	cmd.PersistentFlags().BoolVarP(&o.withoutUnit, "without-unit", "", o.withoutUnit, `Do not print size with unit string.`)
+
+	// color options
+	cmd.PersistentFlags().BoolVarP(&o.nocolor, "no-color", "", o.nocolor, `Print without ansi color.`)
+	cmd.PersistentFlags().BoolVarP(&o.emojiStatus, "emoji", "", o.emojiStatus, `Let's smile!! 😃 😭`)
+	cmd.PersistentFlags().Int64VarP(&o.warnThreshold, "warn-threshold", "", o.warnThreshold, `Threshold of warn(yellow) color for USED column.`)
+	cmd.PersistentFlags().Int64VarP(&o.critThreshold, "crit-threshold", "", o.critThreshold, `Threshold of critical(red) color for USED column.`)
+
+	// node and pod options
+	cmd.PersistentFlags().StringVarP(&o.labelSelector, "selector", "l", o.labelSelector, `Selector (label query) to filter on.`)
+	cmd.PersistentFlags().BoolVarP(&o.allNamespaces, "all-namespaces", "", o.allNamespaces, `If present, list pod resources(limits) across all namespaces. Namespace in current context is ignored even if specified with --namespace.`)
+	cmd.PersistentFlags().StringSliceVarP(&o.countPhases, "count-phases", "", o.countPhases, `Pod phases whose resources are counted as used. Default excludes only terminal (Succeeded/Failed) pods as the scheduler does. Terminating pods are counted and shown as PODS/term with --pod.`)
+	cmd.PersistentFlags().StringSliceVarP(&o.resources, "resources", "", o.resources, `Extended resources to show requested, limited and allocatable of nodes (e.g. nvidia.com/gpu,hugepages-2Mi).`)
+	cmd.PersistentFlags().BoolVarP(&o.allResources, "all-resources", "", o.allResources, `Show all extended resources allocatable on the nodes (e.g. nvidia.com/gpu, hugepages-2Mi).`)
+
+	// options of the root command
+	o.addFlags(
+		cmd.Flags(),
+		"pod", "list", "list-image", "list-all", "by-namespace", "by-owner",
+		"no-headers", "no-metrics", "show-free", "total", "summary", "ephemeral-storage",
+		"watch", "interval", "reverse", "names-only", "group-by", "sort-by", "where", "output",
+	)
+
+	o.configFlags.AddFlags(cmd.PersistentFlags())
 
 	// add the klog flags
 	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
 
+	// subcommands share options of the root command
+	cmd.AddCommand(NewCmdTui(f, o))
+	cmd.AddCommand(NewCmdFit(f, o))
+	cmd.AddCommand(NewCmdHeadroom(f, o))
+	cmd.AddCommand(NewCmdDrainSim(f, o))
+	cmd.AddCommand(NewCmdConsolidate(f, o))
+	cmd.AddCommand(NewCmdPending(f, o))
+
 	// version command template
 	cmd.SetVersionTemplate("Version: " + version + ", GitCommit: " + commit + ", BuildDate: " + date + "\n")
 
 	return cmd
 }
 
+// addFlags adds flags of options by name which are not shared by all commands
+func (o *FreeOptions) addFlags(flags *pflag.FlagSet, names ...string) {
+
+	for _, name := range names {
+		switch name {
+		// bool options
+		case "pod":
+			flags.BoolVarP(&o.pod, name, "p", o.pod, `Show pod count and limit.`)
+		case "list":
+			flags.BoolVarP(&o.list, name, "", o.list, `Show container list on node.`)
+		case "list-image":
+			flags.BoolVarP(&o.listContainerImage, name, "", o.listContainerImage, `Show pod list on node with container image.`)
+		case "list-all":
+			flags.BoolVarP(&o.listAll, name, "", o.listAll, `Show pods even if they have no requests/limit`)
+		case "by-namespace":
+			flags.BoolVarP(&o.byNamespace, name, "", o.byNamespace, `Show sum of resources of containers per namespace with share of total allocatable.`)
+		case "by-owner":
+			flags.BoolVarP(&o.byOwner, name, "", o.byOwner, `Show sum of resources of containers per owning workload (Deployment, StatefulSet, DaemonSet, CronJob, etc) with replica count.`)
+		case "no-headers":
+			flags.BoolVarP(&o.noHeaders, name, "", o.noHeaders, `Do not print table headers.`)
+		case "no-metrics":
+			flags.BoolVarP(&o.noMetrics, name, "", o.noMetrics, `Do not print node/pods/containers usage from metrics-server.`)
+		case "show-free":
+			flags.BoolVarP(&o.freeColumns, name, "", o.freeColumns, `Show free (allocatable - requested) and available (allocatable - used) resources.`)
+		case "total":
+			flags.BoolVarP(&o.total, name, "", o.total, `Show TOTAL row of nodes. Percentages are computed from the sums.`)
+		case "summary":
+			flags.BoolVarP(&o.summary, name, "", o.summary, `Show TOTAL, AVG, MIN and MAX rows of nodes.`)
+		case "ephemeral-storage":
+			flags.BoolVarP(&o.ephemeralStorage, name, "", o.ephemeralStorage, `Show ephemeral-storage requested, limited and allocatable of nodes and containers.`)
+		case "watch":
+			flags.BoolVarP(&o.watch, name, "w", o.watch, `Refresh the table every --interval and highlight changed cells until interrupted.`)
+		case "reverse":
+			flags.BoolVarP(&o.reverse, name, "", o.reverse, `Sort in descending order with --sort-by.`)
+		case "names-only":
+			flags.BoolVarP(&o.namesOnly, name, "", o.namesOnly, `Print only names of nodes, or namespace/pod of containers with --list, one per line.`)
+
+		// duration options
+		case "interval":
+			flags.DurationVarP(&o.interval, name, "", o.interval, `Interval of refresh with --watch and tui.`)
+
+		// string options
+		case "group-by":
+			flags.StringArrayVarP(&o.groupBy, name, "", o.groupBy, `Aggregate nodes by the label key. Nodes without the label are aggregated into "<none>". Can be specified multiple times.`)
+		case "sort-by":
+			flags.StringVarP(&o.sortBy, name, "", o.sortBy, `Sort nodes or containers (--list) by the raw value of the column (e.g. name, cpu.use, mem.req%, free.mem, nvidia.com/gpu.req).`)
+		case "where":
+			flags.StringVarP(&o.where, name, "", o.where, `Show only nodes or containers (--list) matched with the expression of keys of --sort-by (e.g. 'mem.req% > 80 || status != Ready'). Operators: == != > >= < <= && || ! ( ). Values are quantities (e.g. 'cpu.req > 500m', 'free.mem < 2Gi').`)
+		case "output":
+			flags.StringVarP(&o.output, name, "o", o.output, `Output format. One of: json|yaml|csv|tsv|custom-columns=...|custom-columns-file=...|go-template=...|go-template-file=...|jsonpath=...|jsonpath-file=... Values are printed in millicores and bytes regardless of unit options.`)
+		}
+	}
+}
+
 // Complete prepares k8s clients
 func (o *FreeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
 
@@ -198,6 +400,12 @@ func (o *FreeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []str
 	// node client
 	o.nodeClient = client.CoreV1().Nodes()
 
+	// workload clients to walk owner references of pods
+	o.batchClient = client.BatchV1()
+
+	// policy client to read PodDisruptionBudgets
+	o.policyClient = client.PolicyV1beta1()
+
 	// metric client
 	config, err := f.ToRESTConfig()
 	if err != nil {
@@ -209,19 +417,31 @@ func (o *FreeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []str
 		return err
 	}
 
+	// pod client records restartable init containers (sidecars) of pods
+	pclient, err := util.NewPodClient(config)
+	if err != nil {
+		return err
+	}
+
+	// apps client records restartable init containers (sidecars) of pod templates
+	o.appsClient, err = util.NewAppsClient(config)
+	if err != nil {
+		return err
+	}
+
 	// pod and metrics client
 	if o.allNamespaces {
 		// --all-namespace flag
-		o.podClient = client.CoreV1().Pods(v1.NamespaceAll)
+		o.podClient = pclient.Pods(v1.NamespaceAll)
 		o.metricsPodClient = mclient.MetricsV1beta1().PodMetricses(v1.NamespaceAll)
 	} else {
 		if *o.configFlags.Namespace == "" {
 			// default namespace is "default"
-			o.podClient = client.CoreV1().Pods(v1.NamespaceDefault)
+			o.podClient = pclient.Pods(v1.NamespaceDefault)
 			o.metricsPodClient = mclient.MetricsV1beta1().PodMetricses(v1.NamespaceDefault)
 		} else {
 			// targeted namespace (--namespace flag)
-			o.podClient = client.CoreV1().Pods(*o.configFlags.Namespace)
+			o.podClient = pclient.Pods(*o.configFlags.Namespace)
 			o.metricsPodClient = mclient.MetricsV1beta1().PodMetricses(*o.configFlags.Namespace)
 		}
 	}
@@ -230,6 +450,8 @@ func (o *FreeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []str
 	// prepare table header
 	o.prepareFreeTableHeader()
 	o.prepareListTableHeader()
+	o.prepareNamespaceTableHeader()
+	o.prepareOwnerTableHeader()
 
 	return nil
 }
@@ -242,14 +464,57 @@ func (o *FreeOptions) Validate() error {
 		return err
 	}
 
+	// validate output format
+	if err := util.ValidateOutputFormat(o.output); err != nil {
+		return err
+	}
+
+	// validate pod phases
+	if err := util.ValidateCountPhases(o.countPhases); err != nil {
+		return err
+	}
+
+	// validate extended resources
+	if err := util.ValidateResources(o.resources); err != nil {
+		return err
+	}
+
+	// validate sort key
+	if err := o.validateSortBy(); err != nil {
+		return err
+	}
+
+	// validate filter expression
+	if err := o.validateWhere(); err != nil {
+		return err
+	}
+
+	// validate watch mode
+	if o.watch {
+		if err := util.ValidateWatch(o.output, o.interval); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Run printing disk usage of images
 func (o *FreeOptions) Run(args []string) error {
 
+	// refresh until interrupted (--watch option)
+	if o.watch {
+		return o.runWatch(args)
+	}
+
+	return o.run(args)
+}
+
+// run collects and prints resources once
+func (o *FreeOptions) run(args []string) error {
+
 	// get nodes
-	nodes, err := util.GetNodes(o.nodeClient, args, o.labelSelector)
+	nodes, err := o.getNodes(args)
 	if err != nil {
 		return nil
 	}
@@ -262,6 +527,16 @@ func (o *FreeOptions) Run(args []string) error {
 		return nil
 	}
 
+	// sum resources per namespace and return
+	if o.byNamespace {
+		return o.showNamespaces(nodes)
+	}
+
+	// sum resources per workload and return
+	if o.byOwner {
+		return o.showOwners(nodes)
+	}
+
 	// print cpu/mem/pod resource usage
 	if err := o.showFree(nodes); err != nil {
 		return err
@@ -279,6 +554,8 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 	hCPUReq := "CPU/req"
 	hCPULim := "CPU/lim"
 	hCPUAlloc := "CPU/alloc"
+	hCPUFree := "CPU/free"
+	hCPUAvail := "CPU/avail"
 	hCPUUseP := "CPU/use%"
 	hCPUReqP := "CPU/req%"
 	hCPULimP := "CPU/lim%"
@@ -286,22 +563,44 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 	hMEMReq := "MEM/req"
 	hMEMLim := "MEM/lim"
 	hMEMAlloc := "MEM/alloc"
+	hMEMFree := "MEM/free"
+	hMEMAvail := "MEM/avail"
 	hMEMUseP := "MEM/use%"
 	hMEMReqP := "MEM/req%"
 	hMEMLimP := "MEM/lim%"
+	hEPHReq := "EPH/req"
+	hEPHLim := "EPH/lim"
+	hEPHAlloc := "EPH/alloc"
+	hEPHFree := "EPH/free"
+	hEPHReqP := "EPH/req%"
+	hEPHLimP := "EPH/lim%"
 	hPods := "PODS"
 	hPodsAlloc := "PODS/alloc"
+	hPodsTerm := "PODS/term"
 	hContainers := "CONTAINERS"
 
+	if len(o.groupBy) > 0 {
+		// --group-by shows label values and number of ready nodes
+		hName = strings.Join(o.groupBy, ",")
+		hStatus = "READY"
+	}
+
 	if !o.nocolor {
 		// hack: avoid breaking column by escape char
-		util.DefaultColor(&hStatus)  // STATUS
-		util.DefaultColor(&hCPUUseP) // CPU/use%
-		util.DefaultColor(&hCPUReqP) // CPU/req%
-		util.DefaultColor(&hCPULimP) // CPU/lim%
-		util.DefaultColor(&hMEMUseP) // MEM/use%
-		util.DefaultColor(&hMEMReqP) // MEM/req%
-		util.DefaultColor(&hMEMLimP) // MEM/lim%
+		util.DefaultColor(&hStatus)   // STATUS
+		util.DefaultColor(&hCPUUseP)  // CPU/use%
+		util.DefaultColor(&hCPUReqP)  // CPU/req%
+		util.DefaultColor(&hCPULimP)  // CPU/lim%
+		util.DefaultColor(&hMEMUseP)  // MEM/use%
+		util.DefaultColor(&hMEMReqP)  // MEM/req%
+		util.DefaultColor(&hMEMLimP)  // MEM/lim%
+		util.DefaultColor(&hCPUFree)  // CPU/free
+		util.DefaultColor(&hCPUAvail) // CPU/avail
+		util.DefaultColor(&hMEMFree)  // MEM/free
+		util.DefaultColor(&hMEMAvail) // MEM/avail
+		util.DefaultColor(&hEPHReqP)  // EPH/req%
+		util.DefaultColor(&hEPHLimP)  // EPH/lim%
+		util.DefaultColor(&hEPHFree)  // EPH/free
 	}
 
 	baseHeader := []string{
@@ -331,10 +630,22 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 		hMEMLimP,
 	}
 
+	ephHeader := []string{
+		hEPHReq,
+		hEPHLim,
+		hEPHAlloc,
+	}
+
+	ephPHeader := []string{
+		hEPHReqP,
+		hEPHLimP,
+	}
+
 	podHeader := []string{
 		hPods,
 		hPodsAlloc,
 		hContainers,
+		hPodsTerm,
 	}
 
 	if !o.noMetrics {
@@ -345,6 +656,17 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 		memPHeader = append([]string{hMEMUseP}, memPHeader...)
 	}
 
+	if o.freeColumns {
+		// insert free columns
+		cpuHeader = append(cpuHeader, hCPUFree)
+		memHeader = append(memHeader, hMEMFree)
+		if !o.noMetrics {
+			cpuHeader = append(cpuHeader, hCPUAvail)
+			memHeader = append(memHeader, hMEMAvail)
+		}
+		ephHeader = append(ephHeader, hEPHFree)
+	}
+
 	// finally, join all columns
 	fth := []string{}
 
@@ -354,6 +676,11 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 	fth = append(fth, memHeader...)
 	fth = append(fth, memPHeader...)
 
+	if o.ephemeralStorage {
+		fth = append(fth, ephHeader...)
+		fth = append(fth, ephPHeader...)
+	}
+
 	if o.pod {
 		fth = append(fth, podHeader...)
 	}
@@ -377,6 +704,8 @@ func (o *FreeOptions) prepareListTableHeader() {
 	hMEMUse := "MEM/use"
 	hMEMReq := "MEM/req"
 	hMEMLim := "MEM/lim"
+	hEPHReq := "EPH/req"
+	hEPHLim := "EPH/lim"
 	hImage := "IMAGE"
 
 	if !o.nocolor {
@@ -410,6 +739,11 @@ func (o *FreeOptions) prepareListTableHeader() {
 		hMEMLim,
 	}
 
+	ephHeader := []string{
+		hEPHReq,
+		hEPHLim,
+	}
+
 	imageHeader := []string{
 		hImage,
 	}
@@ -429,6 +763,10 @@ func (o *FreeOptions) prepareListTableHeader() {
 	lth = append(lth, cpuHeader...)
 	lth = append(lth, memHeader...)
 
+	if o.ephemeralStorage {
+		lth = append(lth, ephHeader...)
+	}
+
 	if o.listContainerImage {
 		lth = append(lth, imageHeader...)
 	}
@@ -436,6 +774,113 @@ func (o *FreeOptions) prepareListTableHeader() {
 	o.listTableHeaders = lth
 }
 
+// prepareNamespaceTableHeader defines table headers for --by-namespace
+func (o *FreeOptions) prepareNamespaceTableHeader() {
+
+	hNameSpace := "NAMESPACE"
+	hPods := "PODS"
+	hContainers := "CONTAINERS"
+
+	nth := []string{hNameSpace}
+
+	if o.pod {
+		nth = append(nth, hPods, hContainers)
+	}
+
+	o.namespaceTableHeaders = append(nth, o.aggregateTableHeader()...)
+}
+
+// prepareOwnerTableHeader defines table headers for --by-owner
+func (o *FreeOptions) prepareOwnerTableHeader() {
+
+	hNameSpace := "NAMESPACE"
+	hKind := "KIND"
+	hName := "NAME"
+	hReplicas := "REPLICAS"
+	hContainers := "CONTAINERS"
+
+	oth := []string{
+		hNameSpace,
+		hKind,
+		hName,
+		hReplicas,
+	}
+
+	if o.pod {
+		oth = append(oth, hContainers)
+	}
+
+	o.ownerTableHeaders = append(oth, o.aggregateTableHeader()...)
+}
+
+// aggregateTableHeader returns cpu and memory headers of aggregated tables
+func (o *FreeOptions) aggregateTableHeader() []string {
+
+	hCPUUse := "CPU/use"
+	hCPUReq := "CPU/req"
+	hCPULim := "CPU/lim"
+	hCPUUseP := "CPU/use%"
+	hCPUReqP := "CPU/req%"
+	hCPULimP := "CPU/lim%"
+	hCPUUseReq := "CPU/use:req"
+	hMEMUse := "MEM/use"
+	hMEMReq := "MEM/req"
+	hMEMLim := "MEM/lim"
+	hMEMUseP := "MEM/use%"
+	hMEMReqP := "MEM/req%"
+	hMEMLimP := "MEM/lim%"
+	hMEMUseReq := "MEM/use:req"
+
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hCPUUseP) // CPU/use%
+		util.DefaultColor(&hCPUReqP) // CPU/req%
+		util.DefaultColor(&hCPULimP) // CPU/lim%
+		util.DefaultColor(&hMEMUseP) // MEM/use%
+		util.DefaultColor(&hMEMReqP) // MEM/req%
+		util.DefaultColor(&hMEMLimP) // MEM/lim%
+	}
+
+	cpuHeader := []string{
+		hCPUReq,
+		hCPULim,
+	}
+
+	cpuPHeader := []string{
+		hCPUReqP,
+		hCPULimP,
+	}
+
+	memHeader := []string{
+		hMEMReq,
+		hMEMLim,
+	}
+
+	memPHeader := []string{
+		hMEMReqP,
+		hMEMLimP,
+	}
+
+	if !o.noMetrics {
+		// insert metrics columns
+		cpuHeader = append([]string{hCPUUse}, cpuHeader...)
+		cpuPHeader = append([]string{hCPUUseP}, cpuPHeader...)
+		cpuPHeader = append(cpuPHeader, hCPUUseReq)
+		memHeader = append([]string{hMEMUse}, memHeader...)
+		memPHeader = append([]string{hMEMUseP}, memPHeader...)
+		memPHeader = append(memPHeader, hMEMUseReq)
+	}
+
+	// finally, join all columns
+	ath := []string{}
+	ath = append(ath, cpuHeader...)
+	ath = append(ath, cpuPHeader...)
+	ath = append(ath, memHeader...)
+	ath = append(ath, memPHeader...)
+
+	return ath
+}
+
 // setMetricsClient sets metrics client
 func (o *FreeOptions) setMetricsClient(config *rest.Config) (*metrics.Clientset, error) {
 
@@ -478,13 +923,19 @@ func (o *FreeOptions) toUnitOrDash(i int64) string {
 	return o.toUnit(i)
 }
 
-// toMilliUnitOrDash returns "-" if "i" is 0, otherwise returns MilliQuantity
+// toMilliUnitOrDash returns "-" if "i" is 0, otherwise returns toMilliUnit()
 func (o *FreeOptions) toMilliUnitOrDash(i int64) string {
 
 	if i == 0 {
 		return "-"
 	}
 
+	return o.toMilliUnit(i)
+}
+
+// toMilliUnit returns MilliQuantity of "i"
+func (o *FreeOptions) toMilliUnit(i int64) string {
+
 	if o.withoutUnit {
 		// return raw value
 		return strconv.FormatInt(i, 10)
@@ -493,6 +944,40 @@ func (o *FreeOptions) toMilliUnitOrDash(i int64) string {
 	return resource.NewMilliQuantity(i, resource.DecimalSI).String()
 }
 
+// toColorFree returns colored strings for free resources
+// negative value (over committed) is Red
+func (o *FreeOptions) toColorFree(s string, i int64) string {
+
+	if o.nocolor {
+		// nothing to do
+		return s
+	}
+
+	if i < 0 {
+		util.Red(&s)
+	} else {
+		util.DefaultColor(&s)
+	}
+
+	return s
+}
+
+// toColorAvailable returns colored strings for available resources
+// "-" is returned if metrics are not available.
+func (o *FreeOptions) toColorAvailable(v *int64, unit func(int64) string) string {
+
+	if v == nil {
+		s := "-"
+		if !o.nocolor {
+			// hack: avoid breaking column by escape char
+			util.DefaultColor(&s)
+		}
+		return s
+	}
+
+	return o.toColorFree(unit(*v), *v)
+}
+
 // toColorPercent returns colored strings
 //        percentage < warn : Green
 // warn < percentage < crit : Yellow
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/makocchi-git/kubectl-free/pkg/table"
 	"github.com/makocchi-git/kubectl-free/pkg/util"
@@ -237,6 +238,23 @@ func TestNewFreeOptions(t *testing.T) {
 		table:              table.NewOutputTable(os.Stdout),
 		noHeaders:          false,
 		noMetrics:          false,
+		freeColumns:        false,
+		total:              false,
+		summary:            false,
+		groupBy:            []string{},
+		output:             "",
+		countPhases:        []string{"Pending", "Running", "Unknown"},
+		byNamespace:        false,
+		byOwner:            false,
+		ephemeralStorage:   false,
+		resources:          []string{},
+		allResources:       false,
+		watch:              false,
+		interval:           5 * time.Second,
+		sortBy:             "",
+		reverse:            false,
+		where:              "",
+		namesOnly:          false,
 	}
 
 	actual := NewFreeOptions(streams)
@@ -289,6 +307,85 @@ func TestNewCmdFree(t *testing.T) {
 		}
 	})
 
+	// Usage of subcommand
+	t.Run("tui usage", func(t *testing.T) {
+		expected := "tui [node...] [flags]"
+		actual, err := executeCommand(rootCmd, "tui", "--help")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected(%s) differ (got: %s)", expected, actual)
+			return
+		}
+	})
+
+	t.Run("fit usage", func(t *testing.T) {
+		expected := "fit [node...] [flags]"
+		actual, err := executeCommand(rootCmd, "fit", "--help")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected(%s) differ (got: %s)", expected, actual)
+			return
+		}
+	})
+
+	t.Run("headroom usage", func(t *testing.T) {
+		expected := "headroom TYPE/NAME [node...] [flags]"
+		actual, err := executeCommand(rootCmd, "headroom", "--help")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected(%s) differ (got: %s)", expected, actual)
+			return
+		}
+	})
+
+	t.Run("drain-sim usage", func(t *testing.T) {
+		expected := "drain-sim NODE [node...] [flags]"
+		actual, err := executeCommand(rootCmd, "drain-sim", "--help")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected(%s) differ (got: %s)", expected, actual)
+			return
+		}
+	})
+
+	t.Run("consolidate usage", func(t *testing.T) {
+		expected := "consolidate [node...] [flags]"
+		actual, err := executeCommand(rootCmd, "consolidate", "--help")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected(%s) differ (got: %s)", expected, actual)
+			return
+		}
+	})
+
+	t.Run("pending usage", func(t *testing.T) {
+		expected := "pending [node...] [flags]"
+		actual, err := executeCommand(rootCmd, "pending", "--help")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected(%s) differ (got: %s)", expected, actual)
+			return
+		}
+	})
+
 	// Unknown option
 	t.Run("unknown option", func(t *testing.T) {
 		expected := "unknown flag: --very-very-bad-option"
@@ -303,6 +400,30 @@ func TestNewCmdFree(t *testing.T) {
 			return
 		}
 	})
+
+	// Options of the root command are not accepted by subcommands
+	var subcommandTests = []struct {
+		subcommand string
+		flag       string
+	}{
+		{"tui", "--list"},
+		{"fit", "--watch"},
+		{"headroom", "--sort-by"},
+		{"drain-sim", "--names-only"},
+		{"consolidate", "--where"},
+		{"pending", "--by-owner"},
+	}
+
+	for _, test := range subcommandTests {
+		t.Run(test.subcommand+" "+test.flag, func(t *testing.T) {
+			expected := "unknown flag: " + test.flag
+			_, err := executeCommand(rootCmd, test.subcommand, test.flag)
+			if err == nil || err.Error() != expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.subcommand, expected, err)
+				return
+			}
+		})
+	}
 }
 
 func TestComplete(t *testing.T) {
@@ -381,6 +502,87 @@ func TestValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("validate output format", func(t *testing.T) {
+
+		o := &FreeOptions{
+			warnThreshold: 25,
+			critThreshold: 50,
+			output:        "wide",
+		}
+
+		err := o.Validate()
+		expected := "unsupported output format: wide (allowed formats: json, yaml, csv, tsv, custom-columns=..., custom-columns-file=..., go-template=..., go-template-file=..., template=..., templatefile=..., jsonpath=..., jsonpath-file=...)"
+		if err == nil || err.Error() != expected {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	})
+
+	t.Run("validate count phases", func(t *testing.T) {
+
+		o := &FreeOptions{
+			warnThreshold: 25,
+			critThreshold: 50,
+			countPhases:   []string{"Running", "Terminating"},
+		}
+
+		err := o.Validate()
+		expected := "unsupported pod phase: Terminating (allowed phases: Pending, Running, Succeeded, Failed, Unknown)"
+		if err == nil || err.Error() != expected {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	})
+
+	t.Run("validate resources", func(t *testing.T) {
+
+		o := &FreeOptions{
+			warnThreshold: 25,
+			critThreshold: 50,
+			resources:     []string{"memory"},
+		}
+
+		err := o.Validate()
+		expected := "unsupported resource: memory (cpu, memory, pods and ephemeral-storage have dedicated columns)"
+		if err == nil || err.Error() != expected {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	})
+
+	t.Run("validate watch", func(t *testing.T) {
+
+		o := &FreeOptions{
+			warnThreshold: 25,
+			critThreshold: 50,
+			watch:         true,
+			output:        "json",
+		}
+
+		err := o.Validate()
+		expected := "--watch is supported only with table output (got: json)"
+		if err == nil || err.Error() != expected {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	})
+
+	t.Run("validate sort key", func(t *testing.T) {
+
+		o := &FreeOptions{
+			warnThreshold: 25,
+			critThreshold: 50,
+			list:          true,
+			sortBy:        "mem.req%",
+		}
+
+		err := o.Validate()
+		if err == nil || !strings.HasPrefix(err.Error(), "unknown sort key: mem.req% (one of: ") {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	})
+
 	t.Run("validate success", func(t *testing.T) {
 
 		o := &FreeOptions{
@@ -550,6 +752,7 @@ func TestPrepareFreeTableHeader(t *testing.T) {
 				"PODS",
 				"PODS/alloc",
 				"CONTAINERS",
+				"PODS/term",
 			},
 		},
 		{
@@ -598,6 +801,329 @@ func TestPrepareFreeTableHeader(t *testing.T) {
 	}
 }
 
+func TestPrepareFreeTableHeaderFreeColumns(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		nometrics   bool
+		expected    []string
+	}{
+		{
+			"free columns",
+			true,
+			[]string{
+				"NAME",
+				"STATUS",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/alloc",
+				"CPU/free",
+				"CPU/req%",
+				"CPU/lim%",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/alloc",
+				"MEM/free",
+				"MEM/req%",
+				"MEM/lim%",
+			},
+		},
+		{
+			"free columns with metrics",
+			false,
+			[]string{
+				"NAME",
+				"STATUS",
+				"CPU/use",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/alloc",
+				"CPU/free",
+				"CPU/avail",
+				"CPU/use%",
+				"CPU/req%",
+				"CPU/lim%",
+				"MEM/use",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/alloc",
+				"MEM/free",
+				"MEM/avail",
+				"MEM/use%",
+				"MEM/req%",
+				"MEM/lim%",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{
+				noMetrics:   test.nometrics,
+				nocolor:     true,
+				freeColumns: true,
+			}
+			o.prepareFreeTableHeader()
+
+			if !reflect.DeepEqual(o.freeTableHeaders, test.expected) {
+				t.Errorf(
+					"[%s] expected(%v) differ (got: %v)",
+					test.description,
+					test.expected,
+					o.freeTableHeaders,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestPrepareFreeTableHeaderGroupBy(t *testing.T) {
+
+	expected := []string{
+		"zone,pool",
+		"READY",
+		"CPU/req",
+		"CPU/lim",
+		"CPU/alloc",
+		"CPU/req%",
+		"CPU/lim%",
+		"MEM/req",
+		"MEM/lim",
+		"MEM/alloc",
+		"MEM/req%",
+		"MEM/lim%",
+	}
+
+	o := &FreeOptions{
+		noMetrics: true,
+		nocolor:   true,
+		groupBy:   []string{"zone", "pool"},
+	}
+	o.prepareFreeTableHeader()
+
+	if !reflect.DeepEqual(o.freeTableHeaders, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, o.freeTableHeaders)
+		return
+	}
+}
+
+func TestPrepareFreeTableHeaderEphemeralStorage(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		freeColumns bool
+		expected    []string
+	}{
+		{
+			"ephemeral-storage",
+			false,
+			[]string{
+				"NAME",
+				"STATUS",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/alloc",
+				"CPU/req%",
+				"CPU/lim%",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/alloc",
+				"MEM/req%",
+				"MEM/lim%",
+				"EPH/req",
+				"EPH/lim",
+				"EPH/alloc",
+				"EPH/req%",
+				"EPH/lim%",
+			},
+		},
+		{
+			"ephemeral-storage with free columns",
+			true,
+			[]string{
+				"NAME",
+				"STATUS",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/alloc",
+				"CPU/free",
+				"CPU/req%",
+				"CPU/lim%",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/alloc",
+				"MEM/free",
+				"MEM/req%",
+				"MEM/lim%",
+				"EPH/req",
+				"EPH/lim",
+				"EPH/alloc",
+				"EPH/free",
+				"EPH/req%",
+				"EPH/lim%",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{
+				noMetrics:        true,
+				nocolor:          true,
+				freeColumns:      test.freeColumns,
+				ephemeralStorage: true,
+			}
+			o.prepareFreeTableHeader()
+
+			if !reflect.DeepEqual(o.freeTableHeaders, test.expected) {
+				t.Errorf(
+					"[%s] expected(%v) differ (got: %v)",
+					test.description,
+					test.expected,
+					o.freeTableHeaders,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestPrepareNamespaceTableHeader(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		pod         bool
+		nometrics   bool
+		expected    []string
+	}{
+		{
+			"default header",
+			false,
+			true,
+			[]string{
+				"NAMESPACE",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/req%",
+				"CPU/lim%",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/req%",
+				"MEM/lim%",
+			},
+		},
+		{
+			"with pod and metrics",
+			true,
+			false,
+			[]string{
+				"NAMESPACE",
+				"PODS",
+				"CONTAINERS",
+				"CPU/use",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/use%",
+				"CPU/req%",
+				"CPU/lim%",
+				"CPU/use:req",
+				"MEM/use",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/use%",
+				"MEM/req%",
+				"MEM/lim%",
+				"MEM/use:req",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			o := &FreeOptions{
+				nocolor:   true,
+				pod:       test.pod,
+				noMetrics: test.nometrics,
+			}
+			o.prepareNamespaceTableHeader()
+
+			if !reflect.DeepEqual(o.namespaceTableHeaders, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, o.namespaceTableHeaders)
+				return
+			}
+		})
+	}
+}
+
+func TestPrepareOwnerTableHeader(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		pod         bool
+		nometrics   bool
+		expected    []string
+	}{
+		{
+			"default header",
+			false,
+			true,
+			[]string{
+				"NAMESPACE",
+				"KIND",
+				"NAME",
+				"REPLICAS",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/req%",
+				"CPU/lim%",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/req%",
+				"MEM/lim%",
+			},
+		},
+		{
+			"with pod",
+			true,
+			true,
+			[]string{
+				"NAMESPACE",
+				"KIND",
+				"NAME",
+				"REPLICAS",
+				"CONTAINERS",
+				"CPU/req",
+				"CPU/lim",
+				"CPU/req%",
+				"CPU/lim%",
+				"MEM/req",
+				"MEM/lim",
+				"MEM/req%",
+				"MEM/lim%",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			o := &FreeOptions{
+				nocolor:   true,
+				pod:       test.pod,
+				noMetrics: test.nometrics,
+			}
+			o.prepareOwnerTableHeader()
+
+			if !reflect.DeepEqual(o.ownerTableHeaders, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, o.ownerTableHeaders)
+				return
+			}
+		})
+	}
+}
+
 func TestPrepareListTableHeader(t *testing.T) {
 
 	colorStatus := "POD STATUS"
@@ -718,6 +1244,35 @@ func TestPrepareListTableHeader(t *testing.T) {
 		})
 	}
 
+	t.Run("ephemeral-storage", func(t *testing.T) {
+		expected := []string{
+			"NODE NAME",
+			"NAMESPACE",
+			"POD NAME",
+			"POD AGE",
+			"POD IP",
+			"POD STATUS",
+			"CONTAINER",
+			"CPU/req",
+			"CPU/lim",
+			"MEM/req",
+			"MEM/lim",
+			"EPH/req",
+			"EPH/lim",
+		}
+
+		o := &FreeOptions{
+			noMetrics:        true,
+			nocolor:          true,
+			ephemeralStorage: true,
+		}
+		o.prepareListTableHeader()
+
+		if !reflect.DeepEqual(o.listTableHeaders, expected) {
+			t.Errorf("expected(%v) differ (got: %v)", expected, o.listTableHeaders)
+			return
+		}
+	})
 }
 
 func TestToUnit(t *testing.T) {
@@ -834,6 +1389,40 @@ func TestToMilliUnitOrDash(t *testing.T) {
 	}
 }
 
+func TestToColorFree(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		s           string
+		i           int64
+		nocolor     bool
+		expected    string
+	}{
+		{"positive", "1K", 1000, false, color.FgDefault.Render("1K")},
+		{"zero", "-", 0, false, color.FgDefault.Render("-")},
+		{"negative", "-1K", -1000, false, color.FgRed.Render("-1K")},
+		{"negative with nocolor", "-1K", -1000, true, "-1K"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{
+				nocolor: test.nocolor,
+			}
+			actual := o.toColorFree(test.s, test.i)
+			if !bytes.Equal([]byte(actual), []byte(test.expected)) {
+				t.Errorf(
+					"[%s] expected(%v) differ (got: %v)",
+					test.description,
+					[]byte(test.expected),
+					[]byte(actual),
+				)
+				return
+			}
+		})
+	}
+}
+
 func TestToColorPercent(t *testing.T) {
 
 	var tests = []struct {
@@ -892,8 +1481,8 @@ func executeCommandC(root *cobra.Command, args ...string) (c *cobra.Command, out
 
 func prepareTestNodeMetricsClient() *fakemetrics.Clientset {
 	fakeMetricsClient := &fakemetrics.Clientset{}
-	fakeMetricsClient.AddReactor("get", "nodes", func(action core.Action) (handled bool, ret runtime.Object, err error) {
-		return true, &testNodeMetrics.Items[0], nil
+	fakeMetricsClient.AddReactor("list", "nodes", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, testNodeMetrics, nil
 	})
 	return fakeMetricsClient
 }
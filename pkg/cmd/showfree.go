@@ -3,50 +3,160 @@ package cmd
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/makocchi-git/kubectl-free/pkg/collector"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
 	"github.com/makocchi-git/kubectl-free/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
 // showFree prints requested and allocatable resources
 func (o *FreeOptions) showFree(nodes []v1.Node) error {
 
+	// extended resources (--resources and --all-resources option)
+	o.resourceNames = o.getResourceNames(nodes)
+
+	// collect resources
+	items, err := o.getNodeResources(nodes)
+	if err != nil {
+		return err
+	}
+
+	// filter nodes by an expression (--where option)
+	items, err = o.whereNodes(items)
+	if err != nil {
+		return err
+	}
+
+	// aggregate nodes by labels (--group-by option)
+	if len(o.groupBy) > 0 {
+		items = getNodeGroups(items, o.groupBy)
+	}
+
+	// sort nodes by a column (--sort-by option)
+	o.sortNodes(items)
+
+	// print node names only (--names-only option)
+	if o.namesOnly {
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		o.printNames(names)
+		return nil
+	}
+
+	// summary rows (--total and --summary option)
+	summary := []types.Node{}
+	if o.total || o.summary {
+		summary = getNodeSummary(items, o.summary)
+	}
+
+	switch o.output {
+	case "":
+		// table output
+	case outputCSV, outputTSV:
+		// delimited output with raw values
+		if !o.noHeaders {
+			o.table.Header = o.freeRecordHeader()
+		}
+		for _, item := range append(items, summary...) {
+			o.table.AddRow(o.freeRecord(item))
+		}
+		return o.printRecords()
+	default:
+		// structured output
+		// labels of nodes are printed only with --group-by to keep the document small
+		if len(o.groupBy) == 0 {
+			for i := range items {
+				items[i].Labels = nil
+			}
+		}
+		list := types.NewNodeList(items)
+		if len(summary) > 0 {
+			list.Summary = summary
+		}
+		return o.printObject(list)
+	}
+
 	// set table header
 	if !o.noHeaders {
-		o.table.Header = o.freeTableHeaders
+		o.table.Header = append(append([]string{}, o.freeTableHeaders...), o.resourceTableHeader()...)
 	}
 
+	for _, item := range items {
+		if len(o.groupBy) > 0 {
+			o.table.AddRow(o.summaryTableRow(item))
+			continue
+		}
+		o.table.AddRow(o.freeTableRow(item))
+	}
+
+	for _, item := range summary {
+		o.table.AddRow(o.summaryTableRow(item))
+	}
+
+	o.table.Print()
+
+	return nil
+}
+
+// getNodeResources returns requested and allocatable resources of nodes
+func (o *FreeOptions) getNodeResources(nodes []v1.Node) ([]types.Node, error) {
+
+	// sum resources of pods per node
+	aggregates, err := o.getNodeAggregates(nodes)
+	if err != nil {
+		return []types.Node{}, err
+	}
+
+	return o.getNodeResourcesFromAggregates(nodes, aggregates)
+}
+
+// getNodeResourcesFromPods returns requested and allocatable resources of nodes from pods already listed
+func (o *FreeOptions) getNodeResourcesFromPods(nodes []v1.Node, pods *v1.PodList) ([]types.Node, error) {
+	return o.getNodeResourcesFromAggregates(nodes, collector.NewAggregates(pods, o.countPhases))
+}
+
+// getNodeResourcesFromAggregates returns requested and allocatable resources of nodes from sum of resources of pods
+func (o *FreeOptions) getNodeResourcesFromAggregates(nodes []v1.Node, aggregates map[string]collector.Aggregate) ([]types.Node, error) {
+
+	items := []types.Node{}
+
+	// list node metrics once and index them by node
+	var nodeMetrics map[string]metricsapiv1beta1.NodeMetrics
+	metricsList, merr := o.listNodeMetrics()
+	if merr != nil {
+		o.printWarning("failed to list node metrics: %v", merr)
+	} else if metricsList != nil {
+		nodeMetrics = util.GetNodeMetricsByName(metricsList)
+	}
+
+	// nodes which have no metrics sample
+	noSample := []string{}
+
 	// node loop
 	for _, node := range nodes {
 
-		var (
-			cpuMetricsUsed  int64
-			memMetricsUsed  int64
-			cpuMetricsUsedP int64
-			memMetricsUsedP int64
-		)
-
 		// node name
 		nodeName := node.ObjectMeta.Name
 
 		// node status
-		nodeStatus, err := util.GetNodeStatus(node, o.emojiStatus)
+		nodeStatus, err := util.GetNodeStatus(node, false)
 		if err != nil {
-			return err
+			return items, err
 		}
 
-		util.SetNodeStatusColor(&nodeStatus, o.nocolor)
-
-		// get pods on node
-		pods, perr := util.GetPods(o.podClient, nodeName)
-		if perr != nil {
-			return perr
-		}
+		// resources of pods on node counted by accounting policy (--count-phases)
+		a := aggregates[nodeName]
 
-		// calculate requested resources by pods
-		cpuRequested, memRequested, cpuLimited, memLimited := util.GetPodResources(*pods)
+		// requested resources by pods
+		cpuRequested, cpuLimited := a.Requested[v1.ResourceCPU], a.Limited[v1.ResourceCPU]
+		memRequested, memLimited := a.Requested[v1.ResourceMemory], a.Limited[v1.ResourceMemory]
+		ephRequested, ephLimited := a.Requested[v1.ResourceEphemeralStorage], a.Limited[v1.ResourceEphemeralStorage]
 
 		// get cpu allocatable
 		cpuAllocatable := node.Status.Allocatable.Cpu().MilliValue()
@@ -54,93 +164,298 @@ func (o *FreeOptions) showFree(nodes []v1.Node) error {
 		// get memoly allocatable
 		memAllocatable := node.Status.Allocatable.Memory().Value()
 
-		// get usage
-		cpuRequestedP := util.GetPercentage(cpuRequested, cpuAllocatable)
-		cpuLimitedP := util.GetPercentage(cpuLimited, cpuAllocatable)
-		memRequestedP := util.GetPercentage(memRequested, memAllocatable)
-		memLimitedP := util.GetPercentage(memLimited, memAllocatable)
-
-		// get metrics
-		if !o.noMetrics && o.metricsNodeClient != nil {
-			nodeMetrics, err := o.metricsNodeClient.Get(nodeName, metav1.GetOptions{})
-			if err == nil {
-				cpuMetricsUsed = nodeMetrics.Usage.Cpu().MilliValue()
-				memMetricsUsed = nodeMetrics.Usage.Memory().Value()
-				cpuMetricsUsedP = util.GetPercentage(cpuMetricsUsed, cpuAllocatable)
-				memMetricsUsedP = util.GetPercentage(memMetricsUsed, memAllocatable)
+		// get ephemeral-storage allocatable
+		ephAllocatable := node.Status.Allocatable.StorageEphemeral().Value()
+
+		item := types.Node{
+			Name:   nodeName,
+			Status: nodeStatus,
+			Labels: node.ObjectMeta.Labels,
+			CPU: types.NodeResource{
+				Requested:        cpuRequested,
+				Limited:          cpuLimited,
+				Allocatable:      cpuAllocatable,
+				Free:             cpuAllocatable - cpuRequested,
+				RequestedPercent: util.GetPercentage(cpuRequested, cpuAllocatable),
+				LimitedPercent:   util.GetPercentage(cpuLimited, cpuAllocatable),
+			},
+			Memory: types.NodeResource{
+				Requested:        memRequested,
+				Limited:          memLimited,
+				Allocatable:      memAllocatable,
+				Free:             memAllocatable - memRequested,
+				RequestedPercent: util.GetPercentage(memRequested, memAllocatable),
+				LimitedPercent:   util.GetPercentage(memLimited, memAllocatable),
+			},
+			EphemeralStorage: types.NodeResource{
+				Requested:        ephRequested,
+				Limited:          ephLimited,
+				Allocatable:      ephAllocatable,
+				Free:             ephAllocatable - ephRequested,
+				RequestedPercent: util.GetPercentage(ephRequested, ephAllocatable),
+				LimitedPercent:   util.GetPercentage(ephLimited, ephAllocatable),
+			},
+			Pods:            a.Pods,
+			PodsAllocatable: node.Status.Allocatable.Pods().Value(),
+			TerminatingPods: a.TerminatingPods,
+			Containers:      a.Containers,
+			Resources:       getExtendedResources(node, a, o.resourceNames),
+		}
+
+		// set metrics
+		if nodeMetrics != nil {
+			if m, ok := nodeMetrics[nodeName]; ok {
+				item.CPU.Used = m.Usage.Cpu().MilliValue()
+				item.Memory.Used = m.Usage.Memory().Value()
+				item.CPU.UsedPercent = util.GetPercentage(item.CPU.Used, cpuAllocatable)
+				item.Memory.UsedPercent = util.GetPercentage(item.Memory.Used, memAllocatable)
+				cpuAvailable := cpuAllocatable - item.CPU.Used
+				memAvailable := memAllocatable - item.Memory.Used
+				item.CPU.Available = &cpuAvailable
+				item.Memory.Available = &memAvailable
+			} else {
+				noSample = append(noSample, nodeName)
 			}
-			// ignore fetching metrics error
 		}
 
-		// create table row
-		// basic row
-		row := []string{
-			nodeName,   // node name
-			nodeStatus, // node status
+		items = append(items, item)
+	}
+
+	if len(noSample) > 0 {
+		o.printWarning("no metrics sample for nodes: %s", strings.Join(noSample, ", "))
+	}
+
+	return items, nil
+}
+
+// freeTableRow returns table row of a node
+func (o *FreeOptions) freeTableRow(n types.Node) []string {
+
+	// node status
+	nodeStatus := n.Status
+	if o.emojiStatus {
+		nodeStatus = util.GetNodeStatusEmoji(nodeStatus)
+	}
+	util.SetNodeStatusColor(&nodeStatus, o.nocolor)
+
+	// create table row
+	// basic row
+	row := []string{
+		n.Name,     // node name
+		nodeStatus, // node status
+	}
+
+	// cpu
+	if !o.noMetrics {
+		row = append(row, o.toMilliUnitOrDash(n.CPU.Used)) // cpu used (from metrics)
+	}
+	row = append(
+		row,
+		o.toMilliUnitOrDash(n.CPU.Requested),   // cpu requested
+		o.toMilliUnitOrDash(n.CPU.Limited),     // cpu limited
+		o.toMilliUnitOrDash(n.CPU.Allocatable), // cpu allocatable
+	)
+	if o.freeColumns {
+		row = append(row, o.toColorFree(o.toMilliUnit(n.CPU.Free), n.CPU.Free)) // cpu free
+		if !o.noMetrics {
+			row = append(row, o.toColorAvailable(n.CPU.Available, o.toMilliUnit)) // cpu available
 		}
+	}
+	if !o.noMetrics {
+		row = append(row, o.toColorPercent(n.CPU.UsedPercent)) // cpu used %
+	}
+	row = append(
+		row,
+		o.toColorPercent(n.CPU.RequestedPercent), // cpu requested %
+		o.toColorPercent(n.CPU.LimitedPercent),   // cpu limited %
+	)
 
-		// cpu
+	// mem
+	if !o.noMetrics {
+		row = append(row, o.toUnitOrDash(n.Memory.Used)) // mem used (from metrics)
+	}
+	row = append(
+		row,
+		o.toUnitOrDash(n.Memory.Requested),   // mem requested
+		o.toUnitOrDash(n.Memory.Limited),     // mem limited
+		o.toUnitOrDash(n.Memory.Allocatable), // mem allocatable
+	)
+	if o.freeColumns {
+		row = append(row, o.toColorFree(o.toUnit(n.Memory.Free), n.Memory.Free)) // mem free
 		if !o.noMetrics {
-			row = append(row, o.toMilliUnitOrDash(cpuMetricsUsed)) // cpu used (from metrics)
+			row = append(row, o.toColorAvailable(n.Memory.Available, o.toUnit)) // mem available
 		}
+	}
+	if !o.noMetrics {
+		row = append(row, o.toColorPercent(n.Memory.UsedPercent)) // mem used %
+	}
+	row = append(
+		row,
+		o.toColorPercent(n.Memory.RequestedPercent), // mem requested %
+		o.toColorPercent(n.Memory.LimitedPercent),   // mem limited %
+	)
+
+	// ephemeral-storage (--ephemeral-storage option)
+	if o.ephemeralStorage {
 		row = append(
 			row,
-			o.toMilliUnitOrDash(cpuRequested),   // cpu requested
-			o.toMilliUnitOrDash(cpuLimited),     // cpu limited
-			o.toMilliUnitOrDash(cpuAllocatable), // cpu allocatable
+			o.toUnitOrDash(n.EphemeralStorage.Requested),   // ephemeral-storage requested
+			o.toUnitOrDash(n.EphemeralStorage.Limited),     // ephemeral-storage limited
+			o.toUnitOrDash(n.EphemeralStorage.Allocatable), // ephemeral-storage allocatable
 		)
-		if !o.noMetrics {
-			row = append(row, o.toColorPercent(cpuMetricsUsedP)) // cpu used %
+		if o.freeColumns {
+			row = append(row, o.toColorFree(o.toUnit(n.EphemeralStorage.Free), n.EphemeralStorage.Free)) // ephemeral-storage free
 		}
 		row = append(
 			row,
-			o.toColorPercent(cpuRequestedP), // cpu requested %
-			o.toColorPercent(cpuLimitedP),   // cpu limited %
+			o.toColorPercent(n.EphemeralStorage.RequestedPercent), // ephemeral-storage requested %
+			o.toColorPercent(n.EphemeralStorage.LimitedPercent),   // ephemeral-storage limited %
 		)
+	}
 
-		// mem
-		if !o.noMetrics {
-			row = append(row, o.toUnitOrDash(memMetricsUsed)) // mem used (from metrics)
-		}
+	// show pod and container (--pod option)
+	if o.pod {
 		row = append(
 			row,
-			o.toUnitOrDash(memRequested),   // mem requested
-			o.toUnitOrDash(memLimited),     // mem limited
-			o.toUnitOrDash(memAllocatable), // mem allocatable
+			fmt.Sprintf("%d", n.Pods),                // pod used
+			strconv.FormatInt(n.PodsAllocatable, 10), // pod allocatable
+			fmt.Sprintf("%d", n.Containers),          // containers
+			strconv.FormatInt(n.TerminatingPods, 10), // pod terminating
 		)
+	}
+
+	// extended resources (--resources option)
+	row = append(row, o.resourceTableColumns(n)...)
+
+	return row
+}
+
+// summaryTableRow returns table row of a summary or a group without node status color
+func (o *FreeOptions) summaryTableRow(n types.Node) []string {
+
+	status := n.Status
+	if status == "" {
+		status = "-"
+	}
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&status)
+	}
+
+	row := o.freeTableRow(n)
+	row[1] = status
+
+	return row
+}
+
+// freeRecordHeader returns stable column keys for csv/tsv output
+func (o *FreeOptions) freeRecordHeader() []string {
+
+	header := []string{"name", "status"}
+
+	if !o.noMetrics {
+		header = append(header, "cpu_used_millicores")
+	}
+	header = append(header, "cpu_requested_millicores", "cpu_limited_millicores", "cpu_allocatable_millicores")
+	if o.freeColumns {
+		header = append(header, "cpu_free_millicores")
 		if !o.noMetrics {
-			row = append(row, o.toColorPercent(memMetricsUsedP)) // mem used %
+			header = append(header, "cpu_available_millicores")
 		}
-		row = append(
-			row,
-			o.toColorPercent(memRequestedP), // mem requested %
-			o.toColorPercent(memLimitedP),   // mem limited %
-		)
+	}
+	if !o.noMetrics {
+		header = append(header, "cpu_used_percent")
+	}
+	header = append(header, "cpu_requested_percent", "cpu_limited_percent")
 
-		// show pod and container (--pod option)
-		if o.pod {
+	if !o.noMetrics {
+		header = append(header, "memory_used_bytes")
+	}
+	header = append(header, "memory_requested_bytes", "memory_limited_bytes", "memory_allocatable_bytes")
+	if o.freeColumns {
+		header = append(header, "memory_free_bytes")
+		if !o.noMetrics {
+			header = append(header, "memory_available_bytes")
+		}
+	}
+	if !o.noMetrics {
+		header = append(header, "memory_used_percent")
+	}
+	header = append(header, "memory_requested_percent", "memory_limited_percent")
+
+	if o.ephemeralStorage {
+		header = append(header, "ephemeral_storage_requested_bytes", "ephemeral_storage_limited_bytes", "ephemeral_storage_allocatable_bytes")
+		if o.freeColumns {
+			header = append(header, "ephemeral_storage_free_bytes")
+		}
+		header = append(header, "ephemeral_storage_requested_percent", "ephemeral_storage_limited_percent")
+	}
+
+	if o.pod {
+		header = append(header, "pods", "pods_allocatable", "containers", "pods_terminating")
+	}
+
+	return append(header, o.resourceRecordHeader()...)
+}
+
+// freeRecord returns raw values of a node for csv/tsv output
+func (o *FreeOptions) freeRecord(n types.Node) []string {
+
+	i := func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	}
 
-			// pod count
-			podCount := util.GetPodCount(*pods)
+	// empty if metrics are not available
+	p := func(v *int64) string {
+		if v == nil {
+			return ""
+		}
+		return i(*v)
+	}
 
-			// container count
-			containerCount := util.GetContainerCount(*pods)
+	record := []string{n.Name, n.Status}
 
-			// get pod allocatable
-			podAllocatable := node.Status.Allocatable.Pods().Value()
+	if !o.noMetrics {
+		record = append(record, i(n.CPU.Used))
+	}
+	record = append(record, i(n.CPU.Requested), i(n.CPU.Limited), i(n.CPU.Allocatable))
+	if o.freeColumns {
+		record = append(record, i(n.CPU.Free))
+		if !o.noMetrics {
+			record = append(record, p(n.CPU.Available))
+		}
+	}
+	if !o.noMetrics {
+		record = append(record, i(n.CPU.UsedPercent))
+	}
+	record = append(record, i(n.CPU.RequestedPercent), i(n.CPU.LimitedPercent))
 
-			row = append(
-				row,
-				fmt.Sprintf("%d", podCount),           // pod used
-				strconv.FormatInt(podAllocatable, 10), // pod allocatable
-				fmt.Sprintf("%d", containerCount),     // containers
-			)
+	if !o.noMetrics {
+		record = append(record, i(n.Memory.Used))
+	}
+	record = append(record, i(n.Memory.Requested), i(n.Memory.Limited), i(n.Memory.Allocatable))
+	if o.freeColumns {
+		record = append(record, i(n.Memory.Free))
+		if !o.noMetrics {
+			record = append(record, p(n.Memory.Available))
 		}
+	}
+	if !o.noMetrics {
+		record = append(record, i(n.Memory.UsedPercent))
+	}
+	record = append(record, i(n.Memory.RequestedPercent), i(n.Memory.LimitedPercent))
 
-		o.table.AddRow(row)
+	if o.ephemeralStorage {
+		record = append(record, i(n.EphemeralStorage.Requested), i(n.EphemeralStorage.Limited), i(n.EphemeralStorage.Allocatable))
+		if o.freeColumns {
+			record = append(record, i(n.EphemeralStorage.Free))
+		}
+		record = append(record, i(n.EphemeralStorage.RequestedPercent), i(n.EphemeralStorage.LimitedPercent))
 	}
 
-	o.table.Print()
+	if o.pod {
+		record = append(record, i(n.Pods), i(n.PodsAllocatable), i(n.Containers), i(n.TerminatingPods))
+	}
 
-	return nil
+	return append(record, o.resourceRecord(n)...)
 }
@@ -2,13 +2,23 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	fake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
 )
 
 func TestShowFree(t *testing.T) {
@@ -53,7 +63,7 @@ func TestShowFree(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node1   Ready   1     2     4     25%   50%   1K    2K    4K    25%   50%   1     110   1",
+				"node1   Ready   1     2     4     25%   50%   1K    2K    4K    25%   50%   1     110   1     0",
 				"",
 			},
 			nil,
@@ -65,7 +75,7 @@ func TestShowFree(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node1   Ready   200m   200m   4     5%    5%    0K    0K    4K    7%    7%    1     110   2",
+				"node1   Ready   200m   200m   4     5%    5%    0K    0K    4K    7%    7%    1     110   2     0",
 				"",
 			},
 			nil,
@@ -147,3 +157,667 @@ func TestShowFree(t *testing.T) {
 
 	})
 }
+
+func TestShowFreeOutput(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		output      string
+		expected    []string
+	}{
+		{
+			"json",
+			"json",
+			[]string{
+				`{`,
+				`    "kind": "NodeList",`,
+				`    "apiVersion": "kubectl-free/v1alpha1",`,
+				`    "items": [`,
+				`        {`,
+				`            "name": "node1",`,
+				`            "status": "Ready",`,
+				`            "cpu": {`,
+				`                "used": 100,`,
+				`                "requested": 1000,`,
+				`                "limited": 2000,`,
+				`                "allocatable": 4000,`,
+				`                "free": 3000,`,
+				`                "available": 3900,`,
+				`                "usedPercent": 2,`,
+				`                "requestedPercent": 25,`,
+				`                "limitedPercent": 50`,
+				`            },`,
+				`            "memory": {`,
+				`                "used": 1024,`,
+				`                "requested": 1000,`,
+				`                "limited": 2000,`,
+				`                "allocatable": 4000,`,
+				`                "free": 3000,`,
+				`                "available": 2976,`,
+				`                "usedPercent": 25,`,
+				`                "requestedPercent": 25,`,
+				`                "limitedPercent": 50`,
+				`            },`,
+				`            "ephemeralStorage": {`,
+				`                "used": 0,`,
+				`                "requested": 0,`,
+				`                "limited": 0,`,
+				`                "allocatable": 0,`,
+				`                "free": 0,`,
+				`                "usedPercent": 0,`,
+				`                "requestedPercent": 0,`,
+				`                "limitedPercent": 0`,
+				`            },`,
+				`            "pods": 1,`,
+				`            "podsAllocatable": 110,`,
+				`            "terminatingPods": 0,`,
+				`            "containers": 1`,
+				`        }`,
+				`    ]`,
+				`}`,
+				``,
+			},
+		},
+		{
+			"yaml",
+			"yaml",
+			[]string{
+				`apiVersion: kubectl-free/v1alpha1`,
+				`items:`,
+				`- containers: 1`,
+				`  cpu:`,
+				`    allocatable: 4000`,
+				`    available: 3900`,
+				`    free: 3000`,
+				`    limited: 2000`,
+				`    limitedPercent: 50`,
+				`    requested: 1000`,
+				`    requestedPercent: 25`,
+				`    used: 100`,
+				`    usedPercent: 2`,
+				`  ephemeralStorage:`,
+				`    allocatable: 0`,
+				`    free: 0`,
+				`    limited: 0`,
+				`    limitedPercent: 0`,
+				`    requested: 0`,
+				`    requestedPercent: 0`,
+				`    used: 0`,
+				`    usedPercent: 0`,
+				`  memory:`,
+				`    allocatable: 4000`,
+				`    available: 2976`,
+				`    free: 3000`,
+				`    limited: 2000`,
+				`    limitedPercent: 50`,
+				`    requested: 1000`,
+				`    requestedPercent: 25`,
+				`    used: 1024`,
+				`    usedPercent: 25`,
+				`  name: node1`,
+				`  pods: 1`,
+				`  podsAllocatable: 110`,
+				`  status: Ready`,
+				`  terminatingPods: 0`,
+				`kind: NodeList`,
+				``,
+			},
+		},
+		{
+			"csv",
+			"csv",
+			[]string{
+				"name,status,cpu_used_millicores,cpu_requested_millicores,cpu_limited_millicores,cpu_allocatable_millicores,cpu_used_percent,cpu_requested_percent,cpu_limited_percent,memory_used_bytes,memory_requested_bytes,memory_limited_bytes,memory_allocatable_bytes,memory_used_percent,memory_requested_percent,memory_limited_percent",
+				"node1,Ready,100,1000,2000,4000,2,25,50,1024,1000,2000,4000,25,25,50",
+				"",
+			},
+		},
+		{
+			"tsv",
+			"tsv",
+			[]string{
+				"name\tstatus\tcpu_used_millicores\tcpu_requested_millicores\tcpu_limited_millicores\tcpu_allocatable_millicores\tcpu_used_percent\tcpu_requested_percent\tcpu_limited_percent\tmemory_used_bytes\tmemory_requested_bytes\tmemory_limited_bytes\tmemory_allocatable_bytes\tmemory_used_percent\tmemory_requested_percent\tmemory_limited_percent",
+				"node1\tReady\t100\t1000\t2000\t4000\t2\t25\t50\t1024\t1000\t2000\t4000\t25\t25\t50",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(&testPods[0])
+			fakeMetricsNodeClient := prepareTestNodeMetricsClient()
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				IOStreams:         genericclioptions.IOStreams{Out: buffer},
+				output:            test.output,
+				table:             table.NewOutputTable(buffer),
+				kByte:             true,
+				podClient:         fakePodClient.CoreV1().Pods("default"),
+				metricsNodeClient: fakeMetricsNodeClient.MetricsV1beta1().NodeMetricses(),
+			}
+
+			if err := o.showFree([]v1.Node{testNodes[0]}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestShowFreeColumns(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		nometrics   bool
+		output      string
+		expected    []string
+	}{
+		{
+			"free columns with metrics",
+			false,
+			"",
+			[]string{
+				"node1   Ready   100m   1     2     4     3     3900m   2%    25%   50%   1K    1K    2K    4K    3K    2K    25%   25%   50%",
+				"",
+			},
+		},
+		{
+			"free columns without metrics",
+			true,
+			"",
+			[]string{
+				"node1   Ready   1     2     4     3     25%   50%   1K    2K    4K    3K    25%   50%",
+				"",
+			},
+		},
+		{
+			"free columns csv",
+			true,
+			"csv",
+			[]string{
+				"node1,Ready,1000,2000,4000,3000,25,50,1000,2000,4000,3000,25,50",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(&testPods[0])
+			fakeMetricsNodeClient := prepareTestNodeMetricsClient()
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:           true,
+				table:             table.NewOutputTable(buffer),
+				noHeaders:         true,
+				noMetrics:         test.nometrics,
+				freeColumns:       true,
+				output:            test.output,
+				podClient:         fakePodClient.CoreV1().Pods("default"),
+				metricsNodeClient: fakeMetricsNodeClient.MetricsV1beta1().NodeMetricses(),
+			}
+
+			if err := o.showFree([]v1.Node{testNodes[0]}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestFreeTableRowZero(t *testing.T) {
+
+	o := &FreeOptions{
+		nocolor:     true,
+		freeColumns: true,
+	}
+
+	available := int64(0)
+	n := types.Node{
+		Name:   "node1",
+		Status: "Ready",
+		CPU:    types.NodeResource{Requested: 4000, Allocatable: 4000, Free: 0, Available: &available},
+		Memory: types.NodeResource{Requested: 4000, Allocatable: 4000, Free: 0},
+	}
+
+	row := o.freeTableRow(n)
+
+	// cpu free, cpu available, mem free and mem available
+	actual := []string{row[6], row[7], row[15], row[16]}
+	expected := []string{"0", "0", "0K", "-"}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestShowFreeSummary(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		total       bool
+		summary     bool
+		expected    []string
+	}{
+		{
+			"total",
+			true,
+			false,
+			[]string{
+				"node1   Ready      1     2     4     25%   50%   1K    2K    4K    25%   50%",
+				"node2   NotReady   1     2     8     12%   25%   1K    2K    8K    12%   25%",
+				"TOTAL   -          2     4     12    16%   33%   2K    4K    12K   16%   33%",
+				"",
+			},
+		},
+		{
+			"summary",
+			false,
+			true,
+			[]string{
+				"node1   Ready      1     2     4     25%   50%   1K    2K    4K    25%   50%",
+				"node2   NotReady   1     2     8     12%   25%   1K    2K    8K    12%   25%",
+				"TOTAL   -          2     4     12    16%   33%   2K    4K    12K   16%   33%",
+				"AVG     -          1     2     6     16%   33%   1K    2K    6K    16%   33%",
+				"MIN     -          1     2     4     12%   25%   1K    2K    4K    12%   25%",
+				"MAX     -          1     2     8     25%   50%   1K    2K    8K    25%   50%",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			// pod1 on node1 and a copy of pod1 on node2
+			pod4 := testPods[0].DeepCopy()
+			pod4.ObjectMeta.Name = "pod4"
+			pod4.Spec.NodeName = "node2"
+			fakePodClient := fake.NewSimpleClientset(&testPods[0], pod4)
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:   true,
+				table:     table.NewOutputTable(buffer),
+				noHeaders: true,
+				noMetrics: true,
+				total:     test.total,
+				summary:   test.summary,
+				podClient: fakePodClient.CoreV1().Pods("default"),
+			}
+
+			if err := o.showFree([]v1.Node{testNodes[0], testNodes[1]}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestShowFreeCountPhases(t *testing.T) {
+
+	// pod1 (Running), pod5 (Pending), pod6 (Failed) and pod7 (Running, terminating) on node1
+	now := metav1.Now()
+	pod5 := testPods[0].DeepCopy()
+	pod5.ObjectMeta.Name = "pod5"
+	pod5.Status.Phase = v1.PodPending
+	pod6 := testPods[0].DeepCopy()
+	pod6.ObjectMeta.Name = "pod6"
+	pod6.Status.Phase = v1.PodFailed
+	pod7 := testPods[0].DeepCopy()
+	pod7.ObjectMeta.Name = "pod7"
+	pod7.ObjectMeta.DeletionTimestamp = &now
+
+	var tests = []struct {
+		description string
+		countPhases []string
+		expected    []string
+	}{
+		{
+			"default excludes only terminal pods",
+			nil,
+			[]string{
+				"node1,Ready,3000,6000,4000,75,150,3000,6000,4000,75,150,3,110,3,1",
+				"",
+			},
+		},
+		{
+			"running only",
+			[]string{"Running"},
+			[]string{
+				"node1,Ready,2000,4000,4000,50,100,2000,4000,4000,50,100,2,110,2,1",
+				"",
+			},
+		},
+		{
+			"failed only",
+			[]string{"Failed"},
+			[]string{
+				"node1,Ready,1000,2000,4000,25,50,1000,2000,4000,25,50,1,110,1,0",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(&testPods[0], pod5, pod6, pod7)
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				output:      "csv",
+				table:       table.NewOutputTable(buffer),
+				pod:         true,
+				noHeaders:   true,
+				noMetrics:   true,
+				countPhases: test.countPhases,
+				podClient:   fakePodClient.CoreV1().Pods("default"),
+			}
+
+			if err := o.showFree([]v1.Node{testNodes[0]}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestShowFreeEphemeralStorage(t *testing.T) {
+
+	node1 := testNodes[0].DeepCopy()
+	node1.Status.Allocatable[v1.ResourceEphemeralStorage] = *resource.NewQuantity(10000, resource.DecimalSI)
+
+	pod1 := testPods[0].DeepCopy()
+	pod1.Spec.Containers[0].Resources.Requests[v1.ResourceEphemeralStorage] = *resource.NewQuantity(3000, resource.DecimalSI)
+	pod1.Spec.Containers[0].Resources.Limits[v1.ResourceEphemeralStorage] = *resource.NewQuantity(6000, resource.DecimalSI)
+
+	var tests = []struct {
+		description string
+		output      string
+		freeColumns bool
+		expected    []string
+	}{
+		{
+			"table",
+			"",
+			false,
+			[]string{
+				"node1   Ready   1     2     4     25%   50%   1K    2K    4K    25%   50%   3K    6K    10K   30%   60%",
+				"",
+			},
+		},
+		{
+			"csv with free columns",
+			"csv",
+			true,
+			[]string{
+				"node1,Ready,1000,2000,4000,3000,25,50,1000,2000,4000,3000,25,50,3000,6000,10000,7000,30,60",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(pod1)
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				output:           test.output,
+				table:            table.NewOutputTable(buffer),
+				kByte:            true,
+				nocolor:          true,
+				noHeaders:        true,
+				noMetrics:        true,
+				freeColumns:      test.freeColumns,
+				ephemeralStorage: true,
+				podClient:        fakePodClient.CoreV1().Pods("default"),
+			}
+
+			if err := o.showFree([]v1.Node{*node1}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, e, buffer.String())
+				return
+			}
+		})
+	}
+}
+
+func TestGetNodeResourcesMetrics(t *testing.T) {
+
+	node3 := testNodes[0].DeepCopy()
+	node3.ObjectMeta.Name = "node3"
+	nodes := []v1.Node{testNodes[0], testNodes[1], *node3}
+
+	var tests = []struct {
+		description  string
+		listErr      error
+		expectedUsed []int64
+		expectedWarn string
+	}{
+		{
+			"node3 has no metrics sample",
+			nil,
+			[]int64{100, 200, 0},
+			"warning: no metrics sample for nodes: node3\n",
+		},
+		{
+			"failed to list node metrics",
+			errors.New("metrics-server is not running"),
+			[]int64{0, 0, 0},
+			"warning: failed to list node metrics: metrics-server is not running\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			listErr := test.listErr
+			fakePodClient := fake.NewSimpleClientset()
+			fakeMetricsClient := &fakemetrics.Clientset{}
+			fakeMetricsClient.AddReactor("list", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+				if listErr != nil {
+					return true, nil, listErr
+				}
+				return true, testNodeMetrics, nil
+			})
+
+			errOut := &bytes.Buffer{}
+			o := &FreeOptions{
+				IOStreams:         genericclioptions.IOStreams{ErrOut: errOut},
+				podClient:         fakePodClient.CoreV1().Pods(""),
+				metricsNodeClient: fakeMetricsClient.MetricsV1beta1().NodeMetricses(),
+			}
+
+			items, err := o.getNodeResources(nodes)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			for i, item := range items {
+				if item.CPU.Used != test.expectedUsed[i] {
+					t.Errorf("[%s] expected(%d) cpu used of %s differ (got: %d)", test.description, test.expectedUsed[i], item.Name, item.CPU.Used)
+					return
+				}
+			}
+
+			if errOut.String() != test.expectedWarn {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expectedWarn, errOut.String())
+				return
+			}
+
+			// node metrics are listed once regardless of number of nodes
+			if calls := len(fakeMetricsClient.Actions()); calls != 1 {
+				t.Errorf("[%s] expected(1) metrics requests differ (got: %d)", test.description, calls)
+				return
+			}
+		})
+	}
+}
+
+// prepareTestCluster returns n nodes and podsPerNode pods on each node
+func prepareTestCluster(n, podsPerNode int) ([]v1.Node, []runtime.Object) {
+
+	nodes := []v1.Node{}
+	pods := []runtime.Object{}
+
+	for i := 0; i < n; i++ {
+		node := testNodes[0].DeepCopy()
+		node.ObjectMeta.Name = fmt.Sprintf("node%d", i)
+		nodes = append(nodes, *node)
+
+		for j := 0; j < podsPerNode; j++ {
+			pod := testPods[0].DeepCopy()
+			pod.ObjectMeta.Name = fmt.Sprintf("pod%d-%d", i, j)
+			pod.Spec.NodeName = node.ObjectMeta.Name
+			pods = append(pods, pod)
+		}
+	}
+
+	return nodes, pods
+}
+
+// countListPods returns number of list requests of pods
+func countListPods(c *fake.Clientset) int {
+	count := 0
+	for _, action := range c.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestListPodsRequests(t *testing.T) {
+
+	// pods are listed per node for a few nodes, otherwise once
+	var tests = []struct {
+		description string
+		nodes       int
+		calls       int
+	}{
+		{"1 node", 1, 1},
+		{"3 nodes", 3, 3},
+		{"10 nodes", 10, 1},
+		{"100 nodes", 100, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			nodes, pods := prepareTestCluster(test.nodes, 2)
+
+			t.Run("free", func(t *testing.T) {
+				fakeClient := fake.NewSimpleClientset(pods...)
+				o := &FreeOptions{
+					noMetrics: true,
+					podClient: fakeClient.CoreV1().Pods(""),
+				}
+
+				items, err := o.getNodeResources(nodes)
+				if err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+					return
+				}
+
+				for _, item := range items {
+					if item.Pods != 2 {
+						t.Errorf("[%s] expected(2) pods on %s differ (got: %d)", test.description, item.Name, item.Pods)
+						return
+					}
+				}
+
+				if calls := countListPods(fakeClient); calls != test.calls {
+					t.Errorf("[%s] expected(%d) list calls differ (got: %d)", test.description, test.calls, calls)
+					return
+				}
+			})
+
+			t.Run("list", func(t *testing.T) {
+				fakeClient := fake.NewSimpleClientset(pods...)
+				o := &FreeOptions{
+					noMetrics: true,
+					podClient: fakeClient.CoreV1().Pods(""),
+				}
+
+				containers, err := o.getContainerResources(nodes)
+				if err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+					return
+				}
+
+				if len(containers) != test.nodes*2 {
+					t.Errorf("[%s] expected(%d) containers differ (got: %d)", test.description, test.nodes*2, len(containers))
+					return
+				}
+
+				if calls := countListPods(fakeClient); calls != test.calls {
+					t.Errorf("[%s] expected(%d) list calls differ (got: %d)", test.description, test.calls, calls)
+					return
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGetNodeResources(b *testing.B) {
+
+	nodes, pods := prepareTestCluster(500, 10)
+	fakeClient := fake.NewSimpleClientset(pods...)
+
+	o := &FreeOptions{
+		noMetrics: true,
+		podClient: fakeClient.CoreV1().Pods(""),
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := o.getNodeResources(nodes); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	b.StopTimer()
+
+	// previously 1 request per node
+	b.Logf("%d nodes: %d pod list requests in %d runs", len(nodes), countListPods(fakeClient), b.N)
+}
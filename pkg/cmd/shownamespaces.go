@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// showNamespaces prints sum of resources of pods per namespace
+func (o *FreeOptions) showNamespaces(nodes []v1.Node) error {
+
+	// collect resources
+	pods, err := o.listPodsOnNodes(nodes)
+	if err != nil {
+		return err
+	}
+
+	items := getNamespaceResources(nodes, pods, o.getContainerMetrics(), o.countPhases)
+
+	switch o.output {
+	case "":
+		// table output
+	case outputCSV, outputTSV:
+		// delimited output with raw values
+		if !o.noHeaders {
+			o.table.Header = o.namespaceRecordHeader()
+		}
+		for _, item := range items {
+			o.table.AddRow(o.namespaceRecord(item))
+		}
+		return o.printRecords()
+	default:
+		// structured output
+		return o.printObject(types.NewNamespaceList(items))
+	}
+
+	// set table header
+	if !o.noHeaders {
+		o.table.Header = o.namespaceTableHeaders
+	}
+
+	for _, item := range items {
+		o.table.AddRow(o.namespaceTableRow(item))
+	}
+
+	o.table.Print()
+
+	return nil
+}
+
+// getNamespaceResources sums effective resources of pods of counted phases per namespace
+// Containers are app containers as CONTAINERS of nodes, and percentages are share of total allocatable of nodes.
+func getNamespaceResources(nodes []v1.Node, pods []v1.Pod, metrics util.ContainerMetricsIndex, phases []string) []types.Namespace {
+
+	cpuAllocatable, memAllocatable := getNodesAllocatable(nodes)
+
+	namespaces := map[string]*types.Namespace{}
+
+	for _, pod := range pods {
+
+		// skip if pod phase is not counted
+		if !util.IsCountedPhase(string(pod.Status.Phase), phases) {
+			continue
+		}
+
+		ns, ok := namespaces[pod.ObjectMeta.Namespace]
+		if !ok {
+			ns = &types.Namespace{Name: pod.ObjectMeta.Namespace}
+			namespaces[pod.ObjectMeta.Namespace] = ns
+		}
+		ns.Pods++
+		ns.Containers += int64(len(pod.Spec.Containers))
+
+		// sum effective resources of pods
+		cpu, mem := getPodResource(pod, metrics)
+		addAggregateResource(&ns.CPU, cpu)
+		addAggregateResource(&ns.Memory, mem)
+	}
+
+	names := []string{}
+	for name := range namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := []types.Namespace{}
+	for _, name := range names {
+		ns := namespaces[name]
+		setAggregateResourcePercentage(&ns.CPU, cpuAllocatable)
+		setAggregateResourcePercentage(&ns.Memory, memAllocatable)
+		items = append(items, *ns)
+	}
+
+	return items
+}
+
+// namespaceTableRow returns table row of a namespace
+func (o *FreeOptions) namespaceTableRow(n types.Namespace) []string {
+
+	row := []string{
+		n.Name, // namespace
+	}
+
+	if o.pod {
+		row = append(
+			row,
+			strconv.FormatInt(n.Pods, 10),       // pods
+			strconv.FormatInt(n.Containers, 10), // containers
+		)
+	}
+
+	return append(row, o.aggregateTableColumns(n.CPU, n.Memory)...)
+}
+
+// namespaceRecordHeader returns stable column keys for csv/tsv output
+func (o *FreeOptions) namespaceRecordHeader() []string {
+
+	header := []string{"namespace"}
+
+	if o.pod {
+		header = append(header, "pods", "containers")
+	}
+
+	return append(header, o.aggregateRecordHeader()...)
+}
+
+// namespaceRecord returns raw values of a namespace for csv/tsv output
+func (o *FreeOptions) namespaceRecord(n types.Namespace) []string {
+
+	record := []string{n.Name}
+
+	if o.pod {
+		record = append(
+			record,
+			strconv.FormatInt(n.Pods, 10),
+			strconv.FormatInt(n.Containers, 10),
+		)
+	}
+
+	return append(record, o.aggregateRecord(n.CPU, n.Memory)...)
+}
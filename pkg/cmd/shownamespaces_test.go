@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetNamespaceResources(t *testing.T) {
+
+	pods := []v1.Pod{
+		testAggregatePod("default", "pod1", v1.PodRunning, nil, []v1.Container{
+			testContainer("container1a", 1000, 2000, 1000, 2000),
+			testContainer("container1b", 1000, 0, 0, 0),
+		}),
+		testAggregatePod("default", "pod2", v1.PodFailed, nil, []v1.Container{
+			testContainer("container2", 1000, 0, 0, 0),
+		}),
+		testAggregatePod("kube-system", "pod1", v1.PodRunning, []v1.Container{
+			testContainer("init3", 0, 0, 300, 0),
+		}, []v1.Container{
+			testContainer("container3", 0, 0, 100, 0),
+		}),
+	}
+
+	metrics := util.ContainerMetricsIndex{
+		"default/pod1/container1a":    testUsage(100, 500),
+		"default/pod1/container1b":    testUsage(100, 0),
+		"kube-system/pod1/container3": testUsage(0, 200),
+	}
+
+	expected := []types.Namespace{
+		{
+			Name: "default",
+			CPU: types.AggregateResource{
+				Used:                 200,
+				Requested:            2000,
+				Limited:              2000,
+				UsedPercent:          5,
+				RequestedPercent:     50,
+				LimitedPercent:       50,
+				UsedRequestedPercent: 10,
+			},
+			Memory: types.AggregateResource{
+				Used:                 500,
+				Requested:            1000,
+				Limited:              2000,
+				UsedPercent:          12,
+				RequestedPercent:     25,
+				LimitedPercent:       50,
+				UsedRequestedPercent: 50,
+			},
+			Pods:       1,
+			Containers: 2,
+		},
+		{
+			Name: "kube-system",
+			Memory: types.AggregateResource{
+				Used:                 200,
+				Requested:            300,
+				UsedPercent:          5,
+				RequestedPercent:     7,
+				UsedRequestedPercent: 66,
+			},
+			Pods:       1,
+			Containers: 1,
+		},
+	}
+
+	// node1 has 4000m cpu and 4000 bytes memory
+	actual := getNamespaceResources([]v1.Node{testNodes[0]}, pods, metrics, util.DefaultCountPhases)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%+v) differ (got: %+v)", expected, actual)
+		return
+	}
+}
+
+func TestShowNamespaces(t *testing.T) {
+
+	expected := []string{
+		"awesome-ns   1     2     200m    200m    5%    5%    0K    0K    7%    7%",
+		"default      2     3     1500m   2500m   37%   62%   2K    3K    50%   75%",
+		"",
+	}
+
+	fakePodClient := fake.NewSimpleClientset(&testPods[0], &testPods[1], &testPods[2])
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:   true,
+		table:     table.NewOutputTable(buffer),
+		pod:       true,
+		noHeaders: true,
+		noMetrics: true,
+		podClient: fakePodClient.CoreV1().Pods(""),
+	}
+
+	if err := o.showNamespaces([]v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	e := strings.Join(expected, "\n")
+	if buffer.String() != e {
+		t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+		return
+	}
+}
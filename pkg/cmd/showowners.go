@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ownerKindPod is kind of workload for pods without controller
+	ownerKindPod = "Pod"
+)
+
+// owner is a key of workload
+type owner struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+// showOwners prints sum of resources of pods per owning workload
+func (o *FreeOptions) showOwners(nodes []v1.Node) error {
+
+	// collect resources
+	pods, err := o.listPodsOnNodes(nodes)
+	if err != nil {
+		return err
+	}
+
+	items := o.getOwnerResources(nodes, pods, o.getContainerMetrics())
+
+	switch o.output {
+	case "":
+		// table output
+	case outputCSV, outputTSV:
+		// delimited output with raw values
+		if !o.noHeaders {
+			o.table.Header = o.ownerRecordHeader()
+		}
+		for _, item := range items {
+			o.table.AddRow(o.ownerRecord(item))
+		}
+		return o.printRecords()
+	default:
+		// structured output
+		return o.printObject(types.NewWorkloadList(items))
+	}
+
+	// set table header
+	if !o.noHeaders {
+		o.table.Header = o.ownerTableHeaders
+	}
+
+	for _, item := range items {
+		o.table.AddRow(o.ownerTableRow(item))
+	}
+
+	o.table.Print()
+
+	return nil
+}
+
+// getOwnerResources sums effective resources of pods of counted phases per owning workload
+// Containers are app containers as CONTAINERS of nodes, and percentages are share of total allocatable of nodes.
+func (o *FreeOptions) getOwnerResources(nodes []v1.Node, pods []v1.Pod, metrics util.ContainerMetricsIndex) []types.Workload {
+
+	cpuAllocatable, memAllocatable := getNodesAllocatable(nodes)
+
+	workloads := map[owner]*types.Workload{}
+	resolved := map[owner]owner{}
+
+	for _, pod := range pods {
+
+		// skip if pod phase is not counted
+		if !util.IsCountedPhase(string(pod.Status.Phase), o.countPhases) {
+			continue
+		}
+
+		// pod without controller is a workload itself
+		ref := owner{namespace: pod.ObjectMeta.Namespace, kind: ownerKindPod, name: pod.ObjectMeta.Name}
+		if c := metav1.GetControllerOf(&pod); c != nil {
+			ref.kind, ref.name = c.Kind, c.Name
+		}
+
+		key, ok := resolved[ref]
+		if !ok {
+			key = o.getTopOwner(ref)
+			resolved[ref] = key
+		}
+
+		w, ok := workloads[key]
+		if !ok {
+			w = &types.Workload{Namespace: key.namespace, OwnerKind: key.kind, OwnerName: key.name}
+			workloads[key] = w
+		}
+		w.Replicas++
+		w.Containers += int64(len(pod.Spec.Containers))
+
+		// sum effective resources of pods
+		cpu, mem := getPodResource(pod, metrics)
+		addAggregateResource(&w.CPU, cpu)
+		addAggregateResource(&w.Memory, mem)
+	}
+
+	keys := []owner{}
+	for key := range workloads {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].namespace != keys[j].namespace {
+			return keys[i].namespace < keys[j].namespace
+		}
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	items := []types.Workload{}
+	for _, key := range keys {
+		w := workloads[key]
+		setAggregateResourcePercentage(&w.CPU, cpuAllocatable)
+		setAggregateResourcePercentage(&w.Memory, memAllocatable)
+		items = append(items, *w)
+	}
+
+	return items
+}
+
+// getTopOwner walks owner references from ReplicaSet to Deployment and from Job to CronJob
+// The given owner is returned as is if the parent can not be found (e.g. no permission)
+func (o *FreeOptions) getTopOwner(ref owner) owner {
+
+	var parent *metav1.OwnerReference
+
+	switch ref.kind {
+	case "ReplicaSet":
+		if o.appsClient == nil {
+			return ref
+		}
+		rs, err := o.appsClient.ReplicaSets(ref.namespace).Get(ref.name, metav1.GetOptions{})
+		if err != nil {
+			return ref
+		}
+		parent = metav1.GetControllerOf(rs)
+	case "Job":
+		if o.batchClient == nil {
+			return ref
+		}
+		job, err := o.batchClient.Jobs(ref.namespace).Get(ref.name, metav1.GetOptions{})
+		if err != nil {
+			return ref
+		}
+		parent = metav1.GetControllerOf(job)
+	}
+
+	if parent == nil {
+		return ref
+	}
+
+	return owner{namespace: ref.namespace, kind: parent.Kind, name: parent.Name}
+}
+
+// ownerTableRow returns table row of a workload
+func (o *FreeOptions) ownerTableRow(w types.Workload) []string {
+
+	row := []string{
+		w.Namespace,                       // namespace
+		w.OwnerKind,                       // kind
+		w.OwnerName,                       // name
+		strconv.FormatInt(w.Replicas, 10), // replicas
+	}
+
+	if o.pod {
+		row = append(row, strconv.FormatInt(w.Containers, 10)) // containers
+	}
+
+	return append(row, o.aggregateTableColumns(w.CPU, w.Memory)...)
+}
+
+// ownerRecordHeader returns stable column keys for csv/tsv output
+func (o *FreeOptions) ownerRecordHeader() []string {
+
+	header := []string{"namespace", "kind", "name", "replicas"}
+
+	if o.pod {
+		header = append(header, "containers")
+	}
+
+	return append(header, o.aggregateRecordHeader()...)
+}
+
+// ownerRecord returns raw values of a workload for csv/tsv output
+func (o *FreeOptions) ownerRecord(w types.Workload) []string {
+
+	record := []string{
+		w.Namespace,
+		w.OwnerKind,
+		w.OwnerName,
+		strconv.FormatInt(w.Replicas, 10),
+	}
+
+	if o.pod {
+		record = append(record, strconv.FormatInt(w.Containers, 10))
+	}
+
+	return append(record, o.aggregateRecord(w.CPU, w.Memory)...)
+}
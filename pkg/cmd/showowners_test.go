@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+var isController = true
+
+var testReplicaSet = &appsv1.ReplicaSet{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "web-abc",
+		Namespace: "default",
+		OwnerReferences: []metav1.OwnerReference{
+			{Kind: "Deployment", Name: "web", Controller: &isController},
+		},
+	},
+}
+
+var testJob = &batchv1.Job{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:      "backup-123",
+		Namespace: "default",
+		OwnerReferences: []metav1.OwnerReference{
+			{Kind: "CronJob", Name: "backup", Controller: &isController},
+		},
+	},
+}
+
+func TestGetTopOwner(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		ref         owner
+		expected    owner
+	}{
+		{
+			"replicaset owned by deployment",
+			owner{namespace: "default", kind: "ReplicaSet", name: "web-abc"},
+			owner{namespace: "default", kind: "Deployment", name: "web"},
+		},
+		{
+			"job owned by cronjob",
+			owner{namespace: "default", kind: "Job", name: "backup-123"},
+			owner{namespace: "default", kind: "CronJob", name: "backup"},
+		},
+		{
+			"replicaset not found",
+			owner{namespace: "default", kind: "ReplicaSet", name: "orphan-rs"},
+			owner{namespace: "default", kind: "ReplicaSet", name: "orphan-rs"},
+		},
+		{
+			"replicaset in other namespace",
+			owner{namespace: "awesome-ns", kind: "ReplicaSet", name: "web-abc"},
+			owner{namespace: "awesome-ns", kind: "ReplicaSet", name: "web-abc"},
+		},
+		{
+			"statefulset",
+			owner{namespace: "default", kind: "StatefulSet", name: "db"},
+			owner{namespace: "default", kind: "StatefulSet", name: "db"},
+		},
+	}
+
+	fakeClient := fake.NewSimpleClientset(testReplicaSet, testJob)
+
+	o := &FreeOptions{
+		appsClient:  fakeClient.AppsV1(),
+		batchClient: fakeClient.BatchV1(),
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := o.getTopOwner(test.ref)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+
+	t.Run("without clients", func(t *testing.T) {
+		ref := owner{namespace: "default", kind: "ReplicaSet", name: "web-abc"}
+		actual := (&FreeOptions{}).getTopOwner(ref)
+		if actual != ref {
+			t.Errorf("expected(%+v) differ (got: %+v)", ref, actual)
+			return
+		}
+	})
+}
+
+func TestGetOwnerResources(t *testing.T) {
+
+	// pod controlled by kind/name
+	owned := func(pod v1.Pod, kind, name string) v1.Pod {
+		pod.ObjectMeta.OwnerReferences = []metav1.OwnerReference{
+			{Kind: kind, Name: name, Controller: &isController},
+		}
+		return pod
+	}
+
+	web := []v1.Container{testContainer("web", 100, 200, 1000, 2000)}
+
+	pods := []v1.Pod{
+		owned(testAggregatePod("default", "web-abc-1", v1.PodRunning, nil, web), "ReplicaSet", "web-abc"),
+		owned(testAggregatePod("default", "web-abc-2", v1.PodRunning, nil, web), "ReplicaSet", "web-abc"),
+		owned(testAggregatePod("default", "web-abc-3", v1.PodFailed, nil, []v1.Container{
+			testContainer("web", 100, 0, 0, 0),
+		}), "ReplicaSet", "web-abc"),
+		owned(testAggregatePod("default", "backup-123-x", v1.PodRunning, nil, []v1.Container{
+			testContainer("backup", 200, 0, 0, 0),
+		}), "Job", "backup-123"),
+		testAggregatePod("default", "standalone", v1.PodRunning, nil, []v1.Container{
+			testContainer("standalone", 0, 0, 400, 0),
+		}),
+		owned(testAggregatePod("default", "db-0", v1.PodRunning, nil, []v1.Container{
+			testContainer("db", 100, 0, 0, 0),
+			testContainer("sidecar", 0, 0, 100, 0),
+		}), "StatefulSet", "db"),
+		owned(testAggregatePod("default", "orphan-1", v1.PodRunning, nil, []v1.Container{
+			testContainer("orphan", 100, 0, 0, 0),
+		}), "ReplicaSet", "orphan-rs"),
+	}
+
+	metrics := util.ContainerMetricsIndex{
+		"default/web-abc-1/web": testUsage(50, 500),
+		"default/web-abc-2/web": testUsage(50, 500),
+	}
+
+	expected := []types.Workload{
+		{
+			Namespace: "default", OwnerKind: "CronJob", OwnerName: "backup",
+			CPU:      types.AggregateResource{Requested: 200, RequestedPercent: 5},
+			Replicas: 1, Containers: 1,
+		},
+		{
+			Namespace: "default", OwnerKind: "Deployment", OwnerName: "web",
+			CPU: types.AggregateResource{
+				Used: 100, Requested: 200, Limited: 400,
+				UsedPercent: 2, RequestedPercent: 5, LimitedPercent: 10, UsedRequestedPercent: 50,
+			},
+			Memory: types.AggregateResource{
+				Used: 1000, Requested: 2000, Limited: 4000,
+				UsedPercent: 25, RequestedPercent: 50, LimitedPercent: 100, UsedRequestedPercent: 50,
+			},
+			Replicas: 2, Containers: 2,
+		},
+		{
+			Namespace: "default", OwnerKind: "Pod", OwnerName: "standalone",
+			Memory:   types.AggregateResource{Requested: 400, RequestedPercent: 10},
+			Replicas: 1, Containers: 1,
+		},
+		{
+			Namespace: "default", OwnerKind: "ReplicaSet", OwnerName: "orphan-rs",
+			CPU:      types.AggregateResource{Requested: 100, RequestedPercent: 2},
+			Replicas: 1, Containers: 1,
+		},
+		{
+			Namespace: "default", OwnerKind: "StatefulSet", OwnerName: "db",
+			CPU:      types.AggregateResource{Requested: 100, RequestedPercent: 2},
+			Memory:   types.AggregateResource{Requested: 100, RequestedPercent: 2},
+			Replicas: 1, Containers: 2,
+		},
+	}
+
+	fakeClient := fake.NewSimpleClientset(testReplicaSet, testJob)
+
+	o := &FreeOptions{
+		appsClient:  fakeClient.AppsV1(),
+		batchClient: fakeClient.BatchV1(),
+	}
+
+	// node1 has 4000m cpu and 4000 bytes memory
+	actual := o.getOwnerResources([]v1.Node{testNodes[0]}, pods, metrics)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%+v) differ (got: %+v)", expected, actual)
+		return
+	}
+}
+
+func TestShowOwners(t *testing.T) {
+
+	pod1 := testPods[0].DeepCopy()
+	pod1.OwnerReferences = []metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "rs1", Controller: &isController},
+	}
+	pod3 := testPods[2].DeepCopy()
+	pod3.OwnerReferences = []metav1.OwnerReference{
+		{Kind: "DaemonSet", Name: "ds1", Controller: &isController},
+	}
+	rs1 := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rs1",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "Deployment", Name: "deploy1", Controller: &isController},
+			},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		output      string
+		expected    []string
+	}{
+		{
+			"table",
+			"",
+			[]string{
+				"awesome-ns   DaemonSet    ds1       1     200m   200m   5%    5%    0K    0K    7%    7%",
+				"default      Deployment   deploy1   1     1      2      25%   50%   1K    2K    25%   50%",
+				"default      Pod          pod2      1     500m   500m   12%   12%   1K    1K    25%   25%",
+				"",
+			},
+		},
+		{
+			"csv",
+			"csv",
+			[]string{
+				"awesome-ns,DaemonSet,ds1,1,200,200,5,5,300,300,7,7",
+				"default,Deployment,deploy1,1,1000,2000,25,50,1000,2000,25,50",
+				"default,Pod,pod2,1,500,500,12,12,1000,1000,25,25",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakeClient := fake.NewSimpleClientset(pod1, &testPods[1], pod3, rs1)
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:    true,
+				table:      table.NewOutputTable(buffer),
+				noHeaders:  true,
+				noMetrics:  true,
+				output:     test.output,
+				podClient:  fakeClient.CoreV1().Pods(""),
+				appsClient: fakeClient.AppsV1(),
+			}
+
+			if err := o.showOwners([]v1.Node{testNodes[0]}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			e := strings.Join(test.expected, "\n")
+			if buffer.String() != e {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, e, buffer.String())
+				return
+			}
+		})
+	}
+}
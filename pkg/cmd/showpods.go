@@ -1,27 +1,90 @@
 package cmd
 
 import (
+	"strconv"
 	"time"
 
+	"github.com/makocchi-git/kubectl-free/pkg/types"
 	"github.com/makocchi-git/kubectl-free/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/duration"
-	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
 func (o *FreeOptions) showPodsOnNode(nodes []v1.Node) error {
 
+	// collect resources
+	containers, err := o.getContainerResources(nodes)
+	if err != nil {
+		return err
+	}
+
+	items := o.filterContainers(containers)
+
+	// filter containers by an expression (--where option)
+	items, err = o.whereContainers(items)
+	if err != nil {
+		return err
+	}
+
+	// sort containers by a column (--sort-by option)
+	o.sortContainers(items)
+
+	// print namespace/pod of containers only (--names-only option)
+	if o.namesOnly {
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Namespace+"/"+item.Pod)
+		}
+		o.printNames(names)
+		return nil
+	}
+
+	switch o.output {
+	case "":
+		// table output
+	case outputCSV, outputTSV:
+		// delimited output with raw values
+		if !o.noHeaders {
+			o.table.Header = o.listRecordHeader()
+		}
+		for _, item := range items {
+			o.table.AddRow(o.listRecord(item))
+		}
+		return o.printRecords()
+	default:
+		// structured output
+		return o.printObject(types.NewContainerList(items))
+	}
+
 	// set table header
 	if !o.noHeaders {
 		o.table.Header = o.listTableHeaders
 	}
 
-	// get pod metrics
-	var podMetrics *metricsapiv1beta1.PodMetricsList
-	if !o.noMetrics && o.metricsPodClient != nil {
-		podMetrics, _ = o.metricsPodClient.List(metav1.ListOptions{})
+	// rows are identified by containers as rows of a node shift when pods are added or removed
+	for _, item := range items {
+		o.table.AddRowWithKey(item.Namespace+"/"+item.Pod+"/"+item.Name, o.listTableRow(item))
+	}
+
+	o.table.Print()
+
+	return nil
+}
+
+// getContainerResources returns requested resources of containers on nodes
+func (o *FreeOptions) getContainerResources(nodes []v1.Node) ([]types.Container, error) {
+
+	items := []types.Container{}
+
+	// get pod metrics and index them by namespace/pod/container
+	containerMetrics := o.getContainerMetrics()
+
+	// pods indexed by node
+	podsByNode, err := o.listPodsByNode(nodes)
+	if err != nil {
+		return items, err
 	}
 
 	// node loop
@@ -30,89 +93,226 @@ func (o *FreeOptions) showPodsOnNode(nodes []v1.Node) error {
 		// node name
 		nodeName := node.ObjectMeta.Name
 
-		// get pods on node
-		pods, perr := util.GetPods(o.podClient, nodeName)
-		if perr != nil {
-			return perr
-		}
+		// pods on node
+		pods := podsByNode[nodeName]
 
 		// node loop
 		for _, pod := range pods.Items {
 
-			var containerCPUUsed int64
-			var containerMEMUsed int64
-
-			// pod information
-			podName := pod.ObjectMeta.Name
-			podNamespace := pod.ObjectMeta.Namespace
-			podIP := pod.Status.PodIP
-			podStatus := util.GetPodStatus(string(pod.Status.Phase), o.nocolor, o.emojiStatus)
-			podCreationTime := pod.ObjectMeta.CreationTimestamp.UTC()
-			podCreationTimeDiff := time.Since(podCreationTime)
-			podAge := "<unknown>"
-			if !podCreationTime.IsZero() {
-				podAge = duration.HumanDuration(podCreationTimeDiff)
+			// controller of the pod
+			var ownerKind, ownerName string
+			if ref := metav1.GetControllerOf(&pod); ref != nil {
+				ownerKind, ownerName = ref.Kind, ref.Name
 			}
 
-			// container loop
-			for _, container := range pod.Spec.Containers {
-				containerName := container.Name
-				containerImage := container.Image
-				cCpuRequested := container.Resources.Requests.Cpu().MilliValue()
-				cCpuLimit := container.Resources.Limits.Cpu().MilliValue()
-				cMemRequested := container.Resources.Requests.Memory().Value()
-				cMemLimit := container.Resources.Limits.Memory().Value()
-
-				if !o.noMetrics && podMetrics != nil {
-					containerCPUUsed, containerMEMUsed = util.GetContainerMetrics(podMetrics, podName, containerName)
-				}
+			// init containers first, then app containers
+			containers := []v1.Container{}
+			containers = append(containers, pod.Spec.InitContainers...)
+			containers = append(containers, pod.Spec.Containers...)
 
-				// skip if the requested/limit resources are not set
-				if !o.listAll {
-					if cCpuRequested == 0 && cCpuLimit == 0 && cMemRequested == 0 && cMemLimit == 0 {
-						continue
-					}
-				}
+			// container loop
+			for i, container := range containers {
 
-				row := []string{
-					nodeName,      // node name
-					podNamespace,  // namespace
-					podName,       // pod name
-					podAge,        // pod age
-					podIP,         // pod ip
-					podStatus,     // pod status
-					containerName, // container name
+				item := types.Container{
+					Node:              nodeName,
+					Namespace:         pod.ObjectMeta.Namespace,
+					Pod:               pod.ObjectMeta.Name,
+					PodIP:             pod.Status.PodIP,
+					PodStatus:         string(pod.Status.Phase),
+					CreationTimestamp: pod.ObjectMeta.CreationTimestamp,
+					Name:              container.Name,
+					Image:             container.Image,
+					Init:              i < len(pod.Spec.InitContainers),
+					Sidecar:           i < len(pod.Spec.InitContainers) && util.IsRestartableInitContainer(pod, container.Name),
+					OwnerKind:         ownerKind,
+					OwnerName:         ownerName,
+					CPU: types.ContainerResource{
+						Requested: container.Resources.Requests.Cpu().MilliValue(),
+						Limited:   container.Resources.Limits.Cpu().MilliValue(),
+					},
+					Memory: types.ContainerResource{
+						Requested: container.Resources.Requests.Memory().Value(),
+						Limited:   container.Resources.Limits.Memory().Value(),
+					},
+					EphemeralStorage: types.ContainerResource{
+						Requested: container.Resources.Requests.StorageEphemeral().Value(),
+						Limited:   container.Resources.Limits.StorageEphemeral().Value(),
+					},
 				}
 
 				if !o.noMetrics {
-					row = append(row, o.toMilliUnitOrDash(containerCPUUsed))
+					item.CPU.Used, item.Memory.Used = containerMetrics.Get(item.Namespace, item.Pod, item.Name)
 				}
 
-				row = append(
-					row,
-					o.toMilliUnitOrDash(cCpuRequested), // container CPU requested
-					o.toMilliUnitOrDash(cCpuLimit),     // container CPU limit
-				)
-
-				if !o.noMetrics {
-					row = append(row, o.toUnitOrDash(containerMEMUsed))
-				}
+				items = append(items, item)
+			}
+		}
+	}
 
-				row = append(
-					row,
-					o.toUnitOrDash(cMemRequested), // Memory requested
-					o.toUnitOrDash(cMemLimit),     // Memory limit
-				)
+	return items, nil
+}
 
-				if o.listContainerImage {
-					row = append(row, containerImage)
-				}
+// filterContainers returns containers which have requested/limit resources unless --list-all
+func (o *FreeOptions) filterContainers(containers []types.Container) []types.Container {
 
-				o.table.AddRow(row)
+	items := []types.Container{}
+	for _, c := range containers {
+		// skip if the requested/limit resources are not set
+		// ephemeral-storage is checked only if it is shown (--ephemeral-storage option)
+		if !o.listAll {
+			noEphemeralStorage := !o.ephemeralStorage || c.EphemeralStorage.Requested == 0 && c.EphemeralStorage.Limited == 0
+			if c.CPU.Requested == 0 && c.CPU.Limited == 0 && c.Memory.Requested == 0 && c.Memory.Limited == 0 && noEphemeralStorage {
+				continue
 			}
 		}
+		items = append(items, c)
 	}
-	o.table.Print()
 
-	return nil
+	return items
+}
+
+// listTableRow returns table row of a container
+func (o *FreeOptions) listTableRow(c types.Container) []string {
+
+	// pod information
+	podStatus := util.GetPodStatus(c.PodStatus, o.nocolor, o.emojiStatus)
+	podCreationTime := c.CreationTimestamp.UTC()
+	podCreationTimeDiff := time.Since(podCreationTime)
+	podAge := "<unknown>"
+	if !podCreationTime.IsZero() {
+		podAge = duration.HumanDuration(podCreationTimeDiff)
+	}
+
+	// container name with init or sidecar marker
+	containerName := c.Name
+	if c.Sidecar {
+		containerName += " (sidecar)"
+	} else if c.Init {
+		containerName += " (init)"
+	}
+
+	row := []string{
+		c.Node,        // node name
+		c.Namespace,   // namespace
+		c.Pod,         // pod name
+		podAge,        // pod age
+		c.PodIP,       // pod ip
+		podStatus,     // pod status
+		containerName, // container name
+	}
+
+	if !o.noMetrics {
+		row = append(row, o.toMilliUnitOrDash(c.CPU.Used))
+	}
+
+	row = append(
+		row,
+		o.toMilliUnitOrDash(c.CPU.Requested), // container CPU requested
+		o.toMilliUnitOrDash(c.CPU.Limited),   // container CPU limit
+	)
+
+	if !o.noMetrics {
+		row = append(row, o.toUnitOrDash(c.Memory.Used))
+	}
+
+	row = append(
+		row,
+		o.toUnitOrDash(c.Memory.Requested), // Memory requested
+		o.toUnitOrDash(c.Memory.Limited),   // Memory limit
+	)
+
+	if o.ephemeralStorage {
+		row = append(
+			row,
+			o.toUnitOrDash(c.EphemeralStorage.Requested), // ephemeral-storage requested
+			o.toUnitOrDash(c.EphemeralStorage.Limited),   // ephemeral-storage limit
+		)
+	}
+
+	if o.listContainerImage {
+		row = append(row, c.Image)
+	}
+
+	return row
+}
+
+// listRecordHeader returns stable column keys for csv/tsv output
+func (o *FreeOptions) listRecordHeader() []string {
+
+	header := []string{
+		"node",
+		"namespace",
+		"pod",
+		"pod_creation_timestamp",
+		"pod_ip",
+		"pod_status",
+		"container",
+		"init_container",
+		"sidecar_container",
+	}
+
+	if !o.noMetrics {
+		header = append(header, "cpu_used_millicores")
+	}
+	header = append(header, "cpu_requested_millicores", "cpu_limited_millicores")
+
+	if !o.noMetrics {
+		header = append(header, "memory_used_bytes")
+	}
+	header = append(header, "memory_requested_bytes", "memory_limited_bytes")
+
+	if o.ephemeralStorage {
+		header = append(header, "ephemeral_storage_requested_bytes", "ephemeral_storage_limited_bytes")
+	}
+
+	if o.listContainerImage {
+		header = append(header, "image")
+	}
+
+	return header
+}
+
+// listRecord returns raw values of a container for csv/tsv output
+func (o *FreeOptions) listRecord(c types.Container) []string {
+
+	i := func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	}
+
+	creationTimestamp := ""
+	if !c.CreationTimestamp.IsZero() {
+		creationTimestamp = c.CreationTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	record := []string{
+		c.Node,
+		c.Namespace,
+		c.Pod,
+		creationTimestamp,
+		c.PodIP,
+		c.PodStatus,
+		c.Name,
+		strconv.FormatBool(c.Init),
+		strconv.FormatBool(c.Sidecar),
+	}
+
+	if !o.noMetrics {
+		record = append(record, i(c.CPU.Used))
+	}
+	record = append(record, i(c.CPU.Requested), i(c.CPU.Limited))
+
+	if !o.noMetrics {
+		record = append(record, i(c.Memory.Used))
+	}
+	record = append(record, i(c.Memory.Requested), i(c.Memory.Limited))
+
+	if o.ephemeralStorage {
+		record = append(record, i(c.EphemeralStorage.Requested), i(c.EphemeralStorage.Limited))
+	}
+
+	if o.listContainerImage {
+		record = append(record, c.Image)
+	}
+
+	return record
 }
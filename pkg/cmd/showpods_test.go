@@ -2,13 +2,22 @@ package cmd
 
 import (
 	"bytes"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	fake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
 )
 
@@ -27,7 +36,7 @@ func TestShowPodsOnNode(t *testing.T) {
 			false,
 			false,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   -     500m   500m   -     1K    1K",
+				"node1   default   pod2   <unknown>   2.3.4.5   Running   container2a   -     500m   500m   -     1K    1K",
 				"",
 			},
 		},
@@ -37,7 +46,7 @@ func TestShowPodsOnNode(t *testing.T) {
 			false,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K",
+				"node1   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K",
 				"",
 			},
 		},
@@ -47,7 +56,7 @@ func TestShowPodsOnNode(t *testing.T) {
 			false,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K    nginx:latest",
+				"node1   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K    nginx:latest",
 				"",
 			},
 		},
@@ -57,8 +66,8 @@ func TestShowPodsOnNode(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K",
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2b   -      -      -     -",
+				"node1   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K",
+				"node1   default   pod2   <unknown>   2.3.4.5   Running   container2b   -      -      -     -",
 				"",
 			},
 		},
@@ -68,8 +77,8 @@ func TestShowPodsOnNode(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K    nginx:latest",
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2b   -      -      -     -     busybox:latest",
+				"node1   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K    nginx:latest",
+				"node1   default   pod2   <unknown>   2.3.4.5   Running   container2b   -      -      -     -     busybox:latest",
 				"",
 			},
 		},
@@ -94,7 +103,7 @@ func TestShowPodsOnNode(t *testing.T) {
 				metricsNodeClient:  fakeMetricsNodeClient.MetricsV1beta1().NodeMetricses(),
 			}
 
-			if err := o.showPodsOnNode([]v1.Node{testNodes[1]}); err != nil {
+			if err := o.showPodsOnNode([]v1.Node{testNodes[0]}); err != nil {
 				t.Errorf("unexpected error: %v", err)
 				return
 			}
@@ -108,3 +117,295 @@ func TestShowPodsOnNode(t *testing.T) {
 		})
 	}
 }
+
+func TestShowPodsOnNodeOutput(t *testing.T) {
+
+	expected := []string{
+		`{`,
+		`    "kind": "ContainerList",`,
+		`    "apiVersion": "kubectl-free/v1alpha1",`,
+		`    "items": [`,
+		`        {`,
+		`            "node": "node1",`,
+		`            "namespace": "default",`,
+		`            "pod": "pod2",`,
+		`            "podIP": "2.3.4.5",`,
+		`            "podStatus": "Running",`,
+		`            "creationTimestamp": null,`,
+		`            "name": "container2a",`,
+		`            "image": "nginx:latest",`,
+		`            "cpu": {`,
+		`                "used": 0,`,
+		`                "requested": 500,`,
+		`                "limited": 500`,
+		`            },`,
+		`            "memory": {`,
+		`                "used": 0,`,
+		`                "requested": 1000,`,
+		`                "limited": 1000`,
+		`            },`,
+		`            "ephemeralStorage": {`,
+		`                "used": 0,`,
+		`                "requested": 0,`,
+		`                "limited": 0`,
+		`            }`,
+		`        }`,
+		`    ]`,
+		`}`,
+		``,
+	}
+
+	buffer := &bytes.Buffer{}
+	fakeClient := fake.NewSimpleClientset(&testPods[1])
+
+	o := &FreeOptions{
+		IOStreams: genericclioptions.IOStreams{Out: buffer},
+		output:    "json",
+		table:     table.NewOutputTable(buffer),
+		noMetrics: true,
+		podClient: fakeClient.CoreV1().Pods(""),
+	}
+
+	if err := o.showPodsOnNode([]v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	e := strings.Join(expected, "\n")
+	if buffer.String() != e {
+		t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+		return
+	}
+}
+
+func TestShowPodsOnNodeCSV(t *testing.T) {
+
+	expected := []string{
+		"node,namespace,pod,pod_creation_timestamp,pod_ip,pod_status,container,init_container,sidecar_container,cpu_requested_millicores,cpu_limited_millicores,memory_requested_bytes,memory_limited_bytes,image",
+		"node1,default,pod2,,2.3.4.5,Running,container2a,false,false,500,500,1000,1000,nginx:latest",
+		"node1,default,pod2,,2.3.4.5,Running,container2b,false,false,0,0,0,0,busybox:latest",
+		"",
+	}
+
+	buffer := &bytes.Buffer{}
+	fakeClient := fake.NewSimpleClientset(&testPods[1])
+
+	o := &FreeOptions{
+		output:             "csv",
+		table:              table.NewOutputTable(buffer),
+		noMetrics:          true,
+		listAll:            true,
+		listContainerImage: true,
+		podClient:          fakeClient.CoreV1().Pods(""),
+	}
+
+	if err := o.showPodsOnNode([]v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	e := strings.Join(expected, "\n")
+	if buffer.String() != e {
+		t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+		return
+	}
+}
+
+func TestShowPodsOnNodeEphemeralStorage(t *testing.T) {
+
+	expected := []string{
+		"node1,default,pod1,,1.2.3.4,Running,container1,false,false,1000,2000,1000,2000,3000,6000",
+		"node1,default,pod1,,1.2.3.4,Running,scratch,false,false,0,0,0,0,5000,0",
+		"",
+	}
+
+	pod1 := testPods[0].DeepCopy()
+	pod1.Spec.Containers[0].Resources.Requests[v1.ResourceEphemeralStorage] = *resource.NewQuantity(3000, resource.DecimalSI)
+	pod1.Spec.Containers[0].Resources.Limits[v1.ResourceEphemeralStorage] = *resource.NewQuantity(6000, resource.DecimalSI)
+
+	// container which requests only ephemeral-storage is listed without --list-all
+	pod1.Spec.Containers = append(pod1.Spec.Containers, v1.Container{
+		Name: "scratch",
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{
+				v1.ResourceEphemeralStorage: *resource.NewQuantity(5000, resource.DecimalSI),
+			},
+		},
+	})
+
+	buffer := &bytes.Buffer{}
+	fakeClient := fake.NewSimpleClientset(pod1)
+
+	o := &FreeOptions{
+		output:           "csv",
+		table:            table.NewOutputTable(buffer),
+		noHeaders:        true,
+		noMetrics:        true,
+		ephemeralStorage: true,
+		podClient:        fakeClient.CoreV1().Pods(""),
+	}
+
+	if err := o.showPodsOnNode([]v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	e := strings.Join(expected, "\n")
+	if buffer.String() != e {
+		t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+		return
+	}
+}
+
+func TestShowPodsOnNodeSamePodName(t *testing.T) {
+
+	// sorted because order of pods from fake client is not stable
+	expected := []string{
+		"",
+		"node1,awesome-ns,web-0,,,Running,web,false,false,20,100,100,2000,1000,1000",
+		"node1,default,web-0,,,Running,web,false,false,10,100,100,1000,1000,1000",
+	}
+
+	// web-0 in default and awesome-ns
+	pods := []runtime.Object{}
+	metrics := &metricsapiv1beta1.PodMetricsList{}
+	for i, ns := range []string{"default", "awesome-ns"} {
+		pods = append(pods, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: ns},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+			Spec: v1.PodSpec{
+				NodeName: "node1",
+				Containers: []v1.Container{
+					{
+						Name: "web",
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+								v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI),
+							},
+							Limits: v1.ResourceList{
+								v1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+								v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			},
+		})
+		metrics.Items = append(metrics.Items, metricsapiv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: ns},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{
+					Name: "web",
+					Usage: v1.ResourceList{
+						v1.ResourceCPU:    *resource.NewMilliQuantity(int64(10*(i+1)), resource.DecimalSI),
+						v1.ResourceMemory: *resource.NewQuantity(int64(1000*(i+1)), resource.DecimalSI),
+					},
+				},
+			},
+		})
+	}
+
+	fakeClient := fake.NewSimpleClientset(pods...)
+	fakeMetricsClient := &fakemetrics.Clientset{}
+	fakeMetricsClient.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, metrics, nil
+	})
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		output:           "csv",
+		table:            table.NewOutputTable(buffer),
+		noHeaders:        true,
+		podClient:        fakeClient.CoreV1().Pods(""),
+		metricsPodClient: fakeMetricsClient.MetricsV1beta1().PodMetricses(""),
+	}
+
+	if err := o.showPodsOnNode([]v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	actual := strings.Split(buffer.String(), "\n")
+	sort.Strings(actual)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestShowPodsOnNodeInitContainer(t *testing.T) {
+
+	expected := []string{
+		"node1   default   init-pod   <unknown>         Running   setup (init)      200m   -     2K    -",
+		"node1   default   init-pod   <unknown>         Running   proxy (sidecar)   300m   -     3K    -",
+		"node1   default   init-pod   <unknown>         Running   app               100m   -     1K    -",
+		"",
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "init-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{util.AnnotationRestartableInitContainers: "proxy"},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+			InitContainers: []v1.Container{
+				{
+					Name: "setup",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(200, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(2000, resource.DecimalSI),
+						},
+					},
+				},
+				{
+					Name: "proxy",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(300, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(3000, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			Containers: []v1.Container{
+				{
+					Name: "app",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	fakeClient := fake.NewSimpleClientset(pod)
+
+	o := &FreeOptions{
+		table:     table.NewOutputTable(buffer),
+		noHeaders: true,
+		noMetrics: true,
+		nocolor:   true,
+		podClient: fakeClient.CoreV1().Pods(""),
+	}
+
+	if err := o.showPodsOnNode([]v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	e := strings.Join(expected, "\n")
+	if buffer.String() != e {
+		t.Errorf("expected(%s) differ (got: %s)", e, buffer.String())
+		return
+	}
+}
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// sortValue is a value to sort rows by, numbers are compared first and then strings
+type sortValue struct {
+	i int64
+	s string
+}
+
+// less returns true if a is sorted before b
+func (a sortValue) less(b sortValue) bool {
+	if a.i != b.i {
+		return a.i < b.i
+	}
+	return a.s < b.s
+}
+
+// nodeSortKeys are --sort-by and --where keys of nodes (e.g. "cpu.req", "mem.use%", "free.mem")
+var nodeSortKeys = newNodeSortKeys()
+
+// extendedSortKeySuffixes are suffixes of --sort-by keys of extended resources (e.g. "nvidia.com/gpu.req%")
+var extendedSortKeySuffixes = []string{".req%", ".req", ".lim%", ".lim", ".alloc"}
+
+// containerSortKeys are --sort-by and --where keys of containers (--list option)
+var containerSortKeys = map[string]func(c types.Container) sortValue{
+	"node":      func(c types.Container) sortValue { return sortValue{s: c.Node} },
+	"namespace": func(c types.Container) sortValue { return sortValue{s: c.Namespace} },
+	"pod":       func(c types.Container) sortValue { return sortValue{s: c.Pod} },
+	"age":       func(c types.Container) sortValue { return sortValue{i: -c.CreationTimestamp.Unix()} },
+	"ip":        func(c types.Container) sortValue { return sortValue{s: c.PodIP} },
+	"status":    func(c types.Container) sortValue { return sortValue{s: c.PodStatus} },
+	"container": func(c types.Container) sortValue { return sortValue{s: c.Name} },
+	"image":     func(c types.Container) sortValue { return sortValue{s: c.Image} },
+	"cpu.use":   func(c types.Container) sortValue { return sortValue{i: c.CPU.Used} },
+	"cpu.req":   func(c types.Container) sortValue { return sortValue{i: c.CPU.Requested} },
+	"cpu.lim":   func(c types.Container) sortValue { return sortValue{i: c.CPU.Limited} },
+	"mem.use":   func(c types.Container) sortValue { return sortValue{i: c.Memory.Used} },
+	"mem.req":   func(c types.Container) sortValue { return sortValue{i: c.Memory.Requested} },
+	"mem.lim":   func(c types.Container) sortValue { return sortValue{i: c.Memory.Limited} },
+	"eph.req":   func(c types.Container) sortValue { return sortValue{i: c.EphemeralStorage.Requested} },
+	"eph.lim":   func(c types.Container) sortValue { return sortValue{i: c.EphemeralStorage.Limited} },
+}
+
+// newNodeSortKeys returns --sort-by keys of nodes
+// Keys of cpu, memory and ephemeral-storage are generated from patterns of their columns.
+func newNodeSortKeys() map[string]func(n types.Node) sortValue {
+
+	keys := map[string]func(n types.Node) sortValue{
+		"name":       func(n types.Node) sortValue { return sortValue{s: n.Name} },
+		"status":     func(n types.Node) sortValue { return sortValue{s: n.Status} },
+		"pods":       func(n types.Node) sortValue { return sortValue{i: n.Pods} },
+		"pods.alloc": func(n types.Node) sortValue { return sortValue{i: n.PodsAllocatable} },
+		"containers": func(n types.Node) sortValue { return sortValue{i: n.Containers} },
+	}
+
+	resources := map[string]func(n types.Node) types.NodeResource{
+		"cpu": func(n types.Node) types.NodeResource { return n.CPU },
+		"mem": func(n types.Node) types.NodeResource { return n.Memory },
+		"eph": func(n types.Node) types.NodeResource { return n.EphemeralStorage },
+	}
+
+	patterns := map[string]func(v types.NodeResource) int64{
+		"%s.use":   func(v types.NodeResource) int64 { return v.Used },
+		"%s.req":   func(v types.NodeResource) int64 { return v.Requested },
+		"%s.lim":   func(v types.NodeResource) int64 { return v.Limited },
+		"%s.alloc": func(v types.NodeResource) int64 { return v.Allocatable },
+		"%s.use%%": func(v types.NodeResource) int64 { return v.UsedPercent },
+		"%s.req%%": func(v types.NodeResource) int64 { return v.RequestedPercent },
+		"%s.lim%%": func(v types.NodeResource) int64 { return v.LimitedPercent },
+		"free.%s":  func(v types.NodeResource) int64 { return v.Free },
+		"avail.%s": func(v types.NodeResource) int64 { return availableOrZero(v.Available) },
+	}
+
+	for prefix, resource := range resources {
+		for pattern, value := range patterns {
+			resource, value := resource, value
+			keys[fmt.Sprintf(pattern, prefix)] = func(n types.Node) sortValue {
+				return sortValue{i: value(resource(n))}
+			}
+		}
+	}
+
+	return keys
+}
+
+// extendedSortKey returns an extended resource name and a suffix of the key, or empty strings
+func extendedSortKey(key string) (string, string) {
+	for _, suffix := range extendedSortKeySuffixes {
+		if name := strings.TrimSuffix(key, suffix); name != key && name != "" {
+			return name, suffix
+		}
+	}
+	return "", ""
+}
+
+// nodeSortValue returns the function to get a value of a node by --sort-by key
+func nodeSortValue(key string) func(n types.Node) sortValue {
+
+	if f, ok := nodeSortKeys[key]; ok {
+		return f
+	}
+
+	name, suffix := extendedSortKey(key)
+	return func(n types.Node) sortValue {
+		r := n.Resources[name]
+		switch suffix {
+		case ".req%":
+			return sortValue{i: r.RequestedPercent}
+		case ".req":
+			return sortValue{i: r.Requested}
+		case ".lim%":
+			return sortValue{i: r.LimitedPercent}
+		case ".lim":
+			return sortValue{i: r.Limited}
+		default:
+			return sortValue{i: r.Allocatable}
+		}
+	}
+}
+
+// sortNodes sorts nodes by --sort-by and --reverse option
+// Nodes which have the same value keep the order of the API.
+func (o *FreeOptions) sortNodes(nodes []types.Node) {
+
+	if o.sortBy == "" {
+		return
+	}
+
+	value := nodeSortValue(o.sortBy)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if o.reverse {
+			return value(nodes[j]).less(value(nodes[i]))
+		}
+		return value(nodes[i]).less(value(nodes[j]))
+	})
+}
+
+// sortContainers sorts containers by --sort-by and --reverse option
+// Containers which have the same value keep the order of the API.
+func (o *FreeOptions) sortContainers(containers []types.Container) {
+
+	if o.sortBy == "" {
+		return
+	}
+
+	value := containerSortKeys[o.sortBy]
+	sort.SliceStable(containers, func(i, j int) bool {
+		if o.reverse {
+			return value(containers[j]).less(value(containers[i]))
+		}
+		return value(containers[i]).less(value(containers[j]))
+	})
+}
+
+// isNodeKey returns true if key is a key of nodes (--sort-by and --where)
+// Keys of extended resources are valid if the resource is shown by --resources or --all-resources.
+func (o *FreeOptions) isNodeKey(key string) bool {
+
+	if _, ok := nodeSortKeys[key]; ok {
+		return true
+	}
+
+	name, _ := extendedSortKey(key)
+	if name == "" || util.IsStandardResource(v1.ResourceName(name)) {
+		return false
+	}
+
+	if o.allResources {
+		return true
+	}
+
+	for _, r := range o.resources {
+		if r == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isContainerKey returns true if key is a key of containers (--sort-by and --where with --list)
+func isContainerKey(key string) bool {
+	_, ok := containerSortKeys[key]
+	return ok
+}
+
+// isKey returns the function to check keys of nodes, or containers with --list
+func (o *FreeOptions) isKey() func(key string) bool {
+	if o.list {
+		return isContainerKey
+	}
+	return o.isNodeKey
+}
+
+// keyNames returns sorted keys of nodes, or containers with --list
+// Keys of extended resources are not included.
+func (o *FreeOptions) keyNames() string {
+
+	keys := []string{}
+
+	if o.list {
+		for key := range containerSortKeys {
+			keys = append(keys, key)
+		}
+	} else {
+		for key := range nodeSortKeys {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return strings.Join(keys, ", ")
+}
+
+// validateSortBy returns an error if --sort-by key is unknown for the output (nodes or --list)
+func (o *FreeOptions) validateSortBy() error {
+
+	if o.sortBy == "" {
+		return nil
+	}
+
+	if o.byNamespace || o.byOwner {
+		return fmt.Errorf("--sort-by is not supported with --by-namespace and --by-owner")
+	}
+
+	if !o.isKey()(o.sortBy) {
+		return fmt.Errorf("unknown sort key: %s (one of: %s)", o.sortBy, o.keyNames())
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateSortBy(t *testing.T) {
+
+	var tests = []struct {
+		description  string
+		sortBy       string
+		list         bool
+		byNamespace  bool
+		resources    []string
+		allResources bool
+		expected     string
+	}{
+		{"no sort key", "", false, false, []string{}, false, ""},
+		{"node key", "mem.req%", false, false, []string{}, false, ""},
+		{"free key", "free.cpu", false, false, []string{}, false, ""},
+		{"container key", "cpu.use", true, false, []string{}, false, ""},
+		{"extended resource", "nvidia.com/gpu.req%", false, false, []string{"nvidia.com/gpu"}, false, ""},
+		{"extended resource limit percent", "nvidia.com/gpu.lim%", false, false, []string{"nvidia.com/gpu"}, false, ""},
+		{"all resources", "hugepages-2Mi.alloc", false, false, []string{}, true, ""},
+		{"not shown resource", "nvidia.com/gpu.req", false, false, []string{}, false, "unknown sort key: nvidia.com/gpu.req"},
+		{"node key with list", "free.mem", true, false, []string{}, false, "unknown sort key: free.mem"},
+		{"unknown key", "foo", false, false, []string{}, true, "unknown sort key: foo"},
+		{"by namespace", "name", false, true, []string{}, false, "--sort-by is not supported with --by-namespace and --by-owner"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{
+				sortBy:       test.sortBy,
+				list:         test.list,
+				byNamespace:  test.byNamespace,
+				resources:    test.resources,
+				allResources: test.allResources,
+			}
+
+			err := o.validateSortBy()
+			if test.expected == "" {
+				if err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+				}
+				return
+			}
+
+			if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+				return
+			}
+		})
+	}
+}
+
+func TestSortNodes(t *testing.T) {
+
+	nodes := []types.Node{
+		{
+			Name:      "node1",
+			CPU:       types.NodeResource{Requested: 900, Free: 100},
+			Memory:    types.NodeResource{RequestedPercent: 9},
+			Resources: map[string]types.NodeResource{"nvidia.com/gpu": {Requested: 1}},
+		},
+		{
+			Name:      "node2",
+			CPU:       types.NodeResource{Requested: 1000, Free: 1000},
+			Memory:    types.NodeResource{RequestedPercent: 10},
+			Resources: map[string]types.NodeResource{"nvidia.com/gpu": {Requested: 2}},
+		},
+		{
+			Name:   "node10",
+			CPU:    types.NodeResource{Requested: 900, Free: 3000},
+			Memory: types.NodeResource{RequestedPercent: 100},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		sortBy      string
+		reverse     bool
+		expected    []string
+	}{
+		{"no sort key", "", true, []string{"node1", "node2", "node10"}},
+		{"name", "name", false, []string{"node1", "node10", "node2"}},
+		{"numeric percentage", "mem.req%", false, []string{"node1", "node2", "node10"}},
+		{"reverse", "mem.req%", true, []string{"node10", "node2", "node1"}},
+		{"stable", "cpu.req", false, []string{"node1", "node10", "node2"}},
+		{"free", "free.cpu", true, []string{"node10", "node2", "node1"}},
+		{"extended resource", "nvidia.com/gpu.req", true, []string{"node2", "node1", "node10"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{sortBy: test.sortBy, reverse: test.reverse}
+
+			items := append([]types.Node{}, nodes...)
+			o.sortNodes(items)
+
+			actual := []string{}
+			for _, item := range items {
+				actual = append(actual, item.Name)
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestSortContainers(t *testing.T) {
+
+	now := metav1.Now()
+	containers := []types.Container{
+		{Name: "c1", CreationTimestamp: metav1.NewTime(now.Add(-100 * time.Second)), Memory: types.ContainerResource{Limited: 2000}},
+		{Name: "c2", CreationTimestamp: now, Memory: types.ContainerResource{Limited: 10000}},
+		{Name: "c3", CreationTimestamp: metav1.NewTime(now.Add(-200 * time.Second)), Memory: types.ContainerResource{Limited: 300}},
+	}
+
+	var tests = []struct {
+		description string
+		sortBy      string
+		reverse     bool
+		expected    []string
+	}{
+		{"no sort key", "", false, []string{"c1", "c2", "c3"}},
+		{"memory limit", "mem.lim", false, []string{"c3", "c1", "c2"}},
+		{"oldest first", "age", true, []string{"c3", "c1", "c2"}},
+		{"container", "container", true, []string{"c3", "c2", "c1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{sortBy: test.sortBy, reverse: test.reverse}
+
+			items := append([]types.Container{}, containers...)
+			o.sortContainers(items)
+
+			actual := []string{}
+			for _, item := range items {
+				actual = append(actual, item.Name)
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestShowFreeSortBy(t *testing.T) {
+
+	expected := strings.Join([]string{
+		"node2   NotReady   1     2     8     12%   25%   1K    2K    8K    12%   25%",
+		"node1   Ready      1     2     4     25%   50%   1K    2K    4K    25%   50%",
+		"",
+	}, "\n")
+
+	// pod1 on node1 and a copy of pod1 on node2
+	pod4 := testPods[0].DeepCopy()
+	pod4.ObjectMeta.Name = "pod4"
+	pod4.Spec.NodeName = "node2"
+	fakePodClient := fake.NewSimpleClientset(&testPods[0], pod4)
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		table:     table.NewOutputTable(buffer),
+		kByte:     true,
+		nocolor:   true,
+		noHeaders: true,
+		noMetrics: true,
+		sortBy:    "mem.alloc",
+		reverse:   true,
+		podClient: fakePodClient.CoreV1().Pods("default"),
+	}
+
+	if err := o.showFree([]v1.Node{testNodes[0], testNodes[1]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		return
+	}
+}
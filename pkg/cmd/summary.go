@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+)
+
+const (
+	summaryTotal = "TOTAL"
+	summaryAvg   = "AVG"
+	summaryMin   = "MIN"
+	summaryMax   = "MAX"
+)
+
+// getNodeSummary returns TOTAL row of nodes (and AVG/MIN/MAX rows if stats is true)
+// Percentages of TOTAL and AVG are recomputed from the sums rather than averaged
+func getNodeSummary(items []types.Node, stats bool) []types.Node {
+
+	if len(items) == 0 {
+		return []types.Node{}
+	}
+
+	total := types.Node{Name: summaryTotal}
+	lo := items[0]
+	hi := items[0]
+	lo.Name, lo.Status, lo.Labels = summaryMin, "", nil
+	hi.Name, hi.Status, hi.Labels = summaryMax, "", nil
+	lo.Resources = copyResources(lo.Resources)
+	hi.Resources = copyResources(hi.Resources)
+
+	// available resources are averaged over nodes with metrics only
+	var cpuMetrics, memMetrics, ephMetrics int64
+
+	for _, item := range items {
+		sumNodeResource(&total.CPU, item.CPU)
+		sumNodeResource(&total.Memory, item.Memory)
+		sumNodeResource(&total.EphemeralStorage, item.EphemeralStorage)
+		total.Pods += item.Pods
+		total.PodsAllocatable += item.PodsAllocatable
+		total.TerminatingPods += item.TerminatingPods
+		total.Containers += item.Containers
+		total.Resources = sumResources(total.Resources, item.Resources)
+		cpuMetrics += countAvailable(item.CPU.Available)
+		memMetrics += countAvailable(item.Memory.Available)
+		ephMetrics += countAvailable(item.EphemeralStorage.Available)
+
+		minMaxNodeResource(&lo.CPU, &hi.CPU, item.CPU)
+		minMaxNodeResource(&lo.Memory, &hi.Memory, item.Memory)
+		minMaxNodeResource(&lo.EphemeralStorage, &hi.EphemeralStorage, item.EphemeralStorage)
+		lo.Pods, hi.Pods = minMax(lo.Pods, hi.Pods, item.Pods)
+		lo.PodsAllocatable, hi.PodsAllocatable = minMax(lo.PodsAllocatable, hi.PodsAllocatable, item.PodsAllocatable)
+		lo.TerminatingPods, hi.TerminatingPods = minMax(lo.TerminatingPods, hi.TerminatingPods, item.TerminatingPods)
+		lo.Containers, hi.Containers = minMax(lo.Containers, hi.Containers, item.Containers)
+		minMaxResources(lo.Resources, hi.Resources, item.Resources)
+	}
+
+	setNodeResourcePercentage(&total.CPU)
+	setNodeResourcePercentage(&total.Memory)
+	setNodeResourcePercentage(&total.EphemeralStorage)
+	setResourcesPercentage(total.Resources)
+
+	if !stats {
+		return []types.Node{total}
+	}
+
+	n := int64(len(items))
+	avg := types.Node{
+		Name:             summaryAvg,
+		CPU:              divNodeResource(total.CPU, n, cpuMetrics),
+		Memory:           divNodeResource(total.Memory, n, memMetrics),
+		EphemeralStorage: divNodeResource(total.EphemeralStorage, n, ephMetrics),
+		Pods:             total.Pods / n,
+		PodsAllocatable:  total.PodsAllocatable / n,
+		TerminatingPods:  total.TerminatingPods / n,
+		Containers:       total.Containers / n,
+		Resources:        divResources(total.Resources, n),
+	}
+
+	return []types.Node{total, avg, lo, hi}
+}
+
+// sumNodeResource adds raw values of r to sum
+func sumNodeResource(sum *types.NodeResource, r types.NodeResource) {
+	sum.Used += r.Used
+	sum.Requested += r.Requested
+	sum.Limited += r.Limited
+	sum.Allocatable += r.Allocatable
+	sum.Free += r.Free
+	sum.Available = addAvailable(sum.Available, r.Available)
+}
+
+// divNodeResource returns raw values of r divided by n with recomputed percentages
+// Available is divided by m, the number of nodes with metrics.
+func divNodeResource(r types.NodeResource, n, m int64) types.NodeResource {
+	d := types.NodeResource{
+		Used:        r.Used / n,
+		Requested:   r.Requested / n,
+		Limited:     r.Limited / n,
+		Allocatable: r.Allocatable / n,
+		Free:        r.Free / n,
+	}
+	if r.Available != nil && m > 0 {
+		a := *r.Available / m
+		d.Available = &a
+	}
+	setNodeResourcePercentage(&d)
+	return d
+}
+
+// setNodeResourcePercentage computes percentages from raw values
+func setNodeResourcePercentage(r *types.NodeResource) {
+	r.UsedPercent = util.GetPercentage(r.Used, r.Allocatable)
+	r.RequestedPercent = util.GetPercentage(r.Requested, r.Allocatable)
+	r.LimitedPercent = util.GetPercentage(r.Limited, r.Allocatable)
+}
+
+// minMaxNodeResource updates lo and hi with each value of r
+func minMaxNodeResource(lo, hi *types.NodeResource, r types.NodeResource) {
+	lo.Used, hi.Used = minMax(lo.Used, hi.Used, r.Used)
+	lo.Requested, hi.Requested = minMax(lo.Requested, hi.Requested, r.Requested)
+	lo.Limited, hi.Limited = minMax(lo.Limited, hi.Limited, r.Limited)
+	lo.Allocatable, hi.Allocatable = minMax(lo.Allocatable, hi.Allocatable, r.Allocatable)
+	lo.Free, hi.Free = minMax(lo.Free, hi.Free, r.Free)
+	lo.Available, hi.Available = minMaxAvailable(lo.Available, hi.Available, r.Available)
+	lo.UsedPercent, hi.UsedPercent = minMax(lo.UsedPercent, hi.UsedPercent, r.UsedPercent)
+	lo.RequestedPercent, hi.RequestedPercent = minMax(lo.RequestedPercent, hi.RequestedPercent, r.RequestedPercent)
+	lo.LimitedPercent, hi.LimitedPercent = minMax(lo.LimitedPercent, hi.LimitedPercent, r.LimitedPercent)
+}
+
+// minMax returns updated lo and hi with v
+func minMax(lo, hi, v int64) (int64, int64) {
+	if v < lo {
+		lo = v
+	}
+	if v > hi {
+		hi = v
+	}
+	return lo, hi
+}
+
+// addAvailable returns sum of available resources of nodes, nil if none of them has metrics
+func addAvailable(sum, v *int64) *int64 {
+	if v == nil {
+		return sum
+	}
+	a := *v
+	if sum != nil {
+		a += *sum
+	}
+	return &a
+}
+
+// countAvailable returns 1 if the node has metrics, otherwise 0
+func countAvailable(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return 1
+}
+
+// minMaxAvailable returns updated lo and hi with available resource v, nodes without metrics are skipped
+func minMaxAvailable(lo, hi, v *int64) (*int64, *int64) {
+	if v == nil {
+		return lo, hi
+	}
+	if lo == nil || *v < *lo {
+		lo = v
+	}
+	if hi == nil || *v > *hi {
+		hi = v
+	}
+	return lo, hi
+}
+
+// availableOrZero returns available resource, 0 if metrics are not available
+func availableOrZero(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+)
+
+func TestGetNodeSummary(t *testing.T) {
+
+	items := []types.Node{
+		{
+			Name:   "node1",
+			Status: "Ready",
+			CPU: types.NodeResource{
+				Requested:        1000,
+				Allocatable:      4000,
+				Free:             3000,
+				RequestedPercent: 25,
+			},
+			Pods:            2,
+			PodsAllocatable: 110,
+			Containers:      3,
+		},
+		{
+			Name:   "node2",
+			Status: "NotReady",
+			CPU: types.NodeResource{
+				Requested:        3000,
+				Allocatable:      8000,
+				Free:             5000,
+				RequestedPercent: 37,
+			},
+			Pods:            4,
+			PodsAllocatable: 110,
+			Containers:      5,
+		},
+	}
+
+	total := types.Node{
+		Name: "TOTAL",
+		CPU: types.NodeResource{
+			Requested:   4000,
+			Allocatable: 12000,
+			Free:        8000,
+			// 4000 * 100 / 12000, not average of 25% and 37%
+			RequestedPercent: 33,
+		},
+		Pods:            6,
+		PodsAllocatable: 220,
+		Containers:      8,
+	}
+
+	avg := types.Node{
+		Name: "AVG",
+		CPU: types.NodeResource{
+			Requested:        2000,
+			Allocatable:      6000,
+			Free:             4000,
+			RequestedPercent: 33,
+		},
+		Pods:            3,
+		PodsAllocatable: 110,
+		Containers:      4,
+	}
+
+	lo := types.Node{
+		Name: "MIN",
+		CPU: types.NodeResource{
+			Requested:        1000,
+			Allocatable:      4000,
+			Free:             3000,
+			RequestedPercent: 25,
+		},
+		Pods:            2,
+		PodsAllocatable: 110,
+		Containers:      3,
+	}
+
+	hi := types.Node{
+		Name: "MAX",
+		CPU: types.NodeResource{
+			Requested:        3000,
+			Allocatable:      8000,
+			Free:             5000,
+			RequestedPercent: 37,
+		},
+		Pods:            4,
+		PodsAllocatable: 110,
+		Containers:      5,
+	}
+
+	var tests = []struct {
+		description string
+		items       []types.Node
+		stats       bool
+		expected    []types.Node
+	}{
+		{"total", items, false, []types.Node{total}},
+		{"summary", items, true, []types.Node{total, avg, lo, hi}},
+		{"no nodes", []types.Node{}, true, []types.Node{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := getNodeSummary(test.items, test.stats)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestGetNodeSummaryAvailable(t *testing.T) {
+
+	available := func(v int64) *int64 {
+		return &v
+	}
+
+	// node3 has no metrics
+	items := []types.Node{
+		{Name: "node1", CPU: types.NodeResource{Allocatable: 4000, Available: available(3000)}},
+		{Name: "node2", CPU: types.NodeResource{Allocatable: 4000, Available: available(1000)}},
+		{Name: "node3", CPU: types.NodeResource{Allocatable: 4000}},
+	}
+
+	var tests = []struct {
+		description string
+		name        string
+		expected    *int64
+	}{
+		{"total", "TOTAL", available(4000)},
+		{"average of nodes with metrics", "AVG", available(2000)},
+		{"min", "MIN", available(1000)},
+		{"max", "MAX", available(3000)},
+	}
+
+	summary := getNodeSummary(items, true)
+
+	for i, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := summary[i]
+			if actual.Name != test.name || !reflect.DeepEqual(actual.CPU.Available, test.expected) {
+				t.Errorf("[%s] expected(%s: %d) differ (got: %s: %v)", test.description, test.name, *test.expected, actual.Name, actual.CPU.Available)
+				return
+			}
+		})
+	}
+}
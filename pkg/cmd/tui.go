@@ -0,0 +1,498 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/term"
+)
+
+var (
+	// tuiLong defines long description of tui
+	tuiLong = templates.LongDesc(`
+		Show resources of Kubernetes nodes in an interactive full-screen view.
+
+		Keys:
+		  j/k, up/down     move cursor
+		  enter            show containers of the node
+		  esc              back to nodes (or cancel filter)
+		  </>, left/right  change sort column
+		  r                reverse sort order
+		  /                filter by name (or label with key=value on nodes)
+		  q                quit
+	`)
+
+	// tuiExample defines command examples of tui
+	tuiExample = templates.Examples(`
+		# Show nodes interactively and refresh every 5 seconds.
+		kubectl free tui
+
+		# Refresh every 10 seconds with pod counts across all namespaces.
+		kubectl free tui --interval 10s --pod --all-namespaces
+	`)
+)
+
+const (
+	// tuiHeaderLines is number of lines above table rows (title, status, help and table header)
+	tuiHeaderLines = 4
+
+	// tuiDefaultHeight is used when the terminal size is not available
+	tuiDefaultHeight = 24
+)
+
+// tuiView is sort, filter and cursor of a view
+type tuiView struct {
+	cursor  int
+	sortBy  int // column index, -1 is the order of the API
+	reverse bool
+	filter  string
+}
+
+// tuiState is state of the interactive view
+type tuiState struct {
+	// node is the node name of container view, empty in node view
+	node string
+
+	// editing is true while typing filter
+	editing bool
+
+	nodeView      tuiView
+	containerView tuiView
+}
+
+// tuiRow is a row of the interactive view
+type tuiRow struct {
+	// name is node name or "namespace/pod/container" to filter by
+	name   string
+	labels map[string]string
+
+	// cells are printed and keys are raw values of cells to sort by
+	cells []string
+	keys  []string
+}
+
+// NewCmdTui is a cobra command of interactive view
+func NewCmdTui(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "tui [node...]",
+		Short:   "Show resources of Kubernetes nodes interactively.",
+		Long:    tuiLong,
+		Example: tuiExample,
+		Run: func(c *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunTui(args))
+		},
+	}
+
+	o.addFlags(cmd.Flags(), "pod", "ephemeral-storage", "show-free", "no-metrics", "list-image", "list-all", "interval")
+
+	return cmd
+}
+
+// newTuiState returns state which shows nodes in the order of the API
+func newTuiState() *tuiState {
+	return &tuiState{
+		nodeView:      tuiView{sortBy: -1},
+		containerView: tuiView{sortBy: -1},
+	}
+}
+
+// view returns current view
+func (s *tuiState) view() *tuiView {
+	if s.node == "" {
+		return &s.nodeView
+	}
+	return &s.containerView
+}
+
+// RunTui runs interactive view until "q" is pressed
+func (o *FreeOptions) RunTui(args []string) error {
+
+	// tui is always a table, only the interval is validated
+	if err := util.ValidateWatch("", o.interval); err != nil {
+		return err
+	}
+
+	tty := term.TTY{In: o.In, Out: o.Out, Raw: true}
+	if !tty.IsTerminalIn() {
+		return fmt.Errorf("tui requires a terminal")
+	}
+
+	// warnings break the screen
+	o.ErrOut = nil
+
+	// watch nodes and pods instead of listing them on each refresh
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := o.startCollector(args, stop); err != nil {
+		return err
+	}
+
+	height := func() int {
+		if size := tty.GetSize(); size != nil && size.Height > 0 {
+			return int(size.Height)
+		}
+		return tuiDefaultHeight
+	}
+
+	return tty.Safe(func() error {
+		keys := make(chan string)
+		go readKeys(o.In, keys)
+
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+
+		return o.tuiLoop(args, newTuiState(), ticker.C, keys, height)
+	})
+}
+
+// readKeys sends key presses to keys until reading fails
+func readKeys(in io.Reader, keys chan<- string) {
+	defer close(keys)
+
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		keys <- string(buf[:n])
+	}
+}
+
+// tuiLoop draws the view and handles keys, resources are collected again on each tick
+func (o *FreeOptions) tuiLoop(args []string, s *tuiState, tick <-chan time.Time, keys <-chan string, height func() int) error {
+
+	var header []string
+	var rows []tuiRow
+	collect := true
+
+	for {
+		if collect {
+			var err error
+			header, rows, err = o.collectTui(args, s)
+			if err != nil {
+				return err
+			}
+		}
+
+		frame := o.renderTui(s, header, rows, height())
+		fmt.Fprint(o.Out, clearScreen+strings.Replace(frame, "\n", "\r\n", -1))
+
+		select {
+		case <-tick:
+			collect = true
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			node := s.node
+			if s.handleKey(key, filterTuiRows(rows, s.view())) {
+				fmt.Fprint(o.Out, clearScreen)
+				return nil
+			}
+			// collect containers of the node when the view is changed
+			collect = node != s.node
+		}
+	}
+}
+
+// handleKey updates state by a key press and returns true to quit
+// rows are rows on the screen to select a node
+func (s *tuiState) handleKey(key string, rows []tuiRow) bool {
+
+	v := s.view()
+
+	// typing filter
+	if s.editing {
+		switch key {
+		case "\r", "\n":
+			s.editing = false
+		case "\x1b":
+			s.editing = false
+			v.filter = ""
+		case "\x7f", "\b":
+			if len(v.filter) > 0 {
+				v.filter = v.filter[:len(v.filter)-1]
+			}
+		default:
+			if len(key) == 1 && key[0] >= ' ' && key[0] <= '~' {
+				v.filter += key
+			}
+		}
+		v.cursor = 0
+		return false
+	}
+
+	switch key {
+	case "q", "\x03":
+		return true
+	case "j", "\x1b[B":
+		v.cursor++
+	case "k", "\x1b[A":
+		v.cursor--
+	case ">", "\x1b[C":
+		v.sortBy++
+	case "<", "\x1b[D":
+		if v.sortBy >= 0 {
+			v.sortBy--
+		}
+	case "r":
+		v.reverse = !v.reverse
+	case "/":
+		s.editing = true
+	case "\r", "\n":
+		if s.node == "" && v.cursor >= 0 && v.cursor < len(rows) {
+			s.node = rows[v.cursor].name
+			s.containerView = tuiView{sortBy: -1}
+		}
+	case "\x1b":
+		if s.node != "" {
+			s.node = ""
+		} else {
+			v.filter = ""
+		}
+	}
+
+	// keep cursor on rows
+	if v.cursor >= len(rows) {
+		v.cursor = len(rows) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+
+	return false
+}
+
+// collectTui collects rows of the current view with the same functions as showFree and showPodsOnNode
+func (o *FreeOptions) collectTui(args []string, s *tuiState) ([]string, []tuiRow, error) {
+
+	nodes, err := o.getNodes(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := []tuiRow{}
+
+	// node view
+	if s.node == "" {
+		o.resourceNames = o.getResourceNames(nodes)
+
+		items, err := o.getNodeResources(nodes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, item := range items {
+			rows = append(rows, tuiRow{
+				name:   item.Name,
+				labels: item.Labels,
+				cells:  o.freeTableRow(item),
+				keys:   o.freeRecord(item),
+			})
+		}
+
+		header := append(append([]string{}, o.freeTableHeaders...), o.resourceTableHeader()...)
+		return header, rows, nil
+	}
+
+	// container view of the node
+	selected := []v1.Node{}
+	for _, node := range nodes {
+		if node.ObjectMeta.Name == s.node {
+			selected = append(selected, node)
+		}
+	}
+
+	containers, err := o.getContainerResources(selected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, c := range o.filterContainers(containers) {
+		rows = append(rows, tuiRow{
+			name:  c.Namespace + "/" + c.Pod + "/" + c.Name,
+			cells: o.listTableRow(c),
+			keys:  o.listSortKeys(c),
+		})
+	}
+
+	return o.listTableHeaders, rows, nil
+}
+
+// listSortKeys returns raw values of a container aligned with listTableRow
+// The init_container and sidecar_container columns of csv/tsv output are not columns of the table.
+func (o *FreeOptions) listSortKeys(c types.Container) []string {
+
+	record := o.listRecord(c)
+
+	keys := []string{}
+	for i, name := range o.listRecordHeader() {
+		if name != "init_container" && name != "sidecar_container" {
+			keys = append(keys, record[i])
+		}
+	}
+
+	return keys
+}
+
+// filterTuiRows returns rows matched with the filter of the view and sorted by the sort column
+// Filter "key=value" matches labels of nodes, otherwise it matches a part of the name.
+func filterTuiRows(rows []tuiRow, v *tuiView) []tuiRow {
+
+	filtered := []tuiRow{}
+	for _, row := range rows {
+		if i := strings.Index(v.filter, "="); i > 0 {
+			if row.labels[v.filter[:i]] != v.filter[i+1:] {
+				continue
+			}
+		} else if !strings.Contains(row.name, v.filter) {
+			continue
+		}
+		filtered = append(filtered, row)
+	}
+
+	if v.sortBy >= 0 {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			a, b := sortKey(filtered[i], v.sortBy), sortKey(filtered[j], v.sortBy)
+			if v.reverse {
+				return lessTuiValue(b, a)
+			}
+			return lessTuiValue(a, b)
+		})
+	} else if v.reverse {
+		for i, j := 0, len(filtered)-1; i < j; i, j = i+1, j-1 {
+			filtered[i], filtered[j] = filtered[j], filtered[i]
+		}
+	}
+
+	return filtered
+}
+
+// sortKey returns raw value of the column
+func sortKey(row tuiRow, column int) string {
+	if column < len(row.keys) {
+		return row.keys[column]
+	}
+	return ""
+}
+
+// lessTuiValue compares values as numbers if both are numbers, otherwise as strings
+func lessTuiValue(a, b string) bool {
+	x, errx := strconv.ParseInt(a, 10, 64)
+	y, erry := strconv.ParseInt(b, 10, 64)
+	if errx == nil && erry == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// renderTui returns a frame of the interactive view which fits in height
+func (o *FreeOptions) renderTui(s *tuiState, header []string, rows []tuiRow, height int) string {
+
+	v := s.view()
+	if v.sortBy >= len(header) {
+		v.sortBy = len(header) - 1
+	}
+	rows = filterTuiRows(rows, v)
+
+	buffer := &bytes.Buffer{}
+
+	// title
+	if s.node == "" {
+		fmt.Fprintf(buffer, "kubectl free tui - %d nodes\n", len(rows))
+	} else {
+		fmt.Fprintf(buffer, "kubectl free tui - %d containers on %s\n", len(rows), s.node)
+	}
+
+	// status
+	order := "asc"
+	if v.reverse {
+		order = "desc"
+	}
+	sortBy := "-"
+	if v.sortBy >= 0 {
+		sortBy = header[v.sortBy]
+	}
+	filter := v.filter
+	if s.editing {
+		filter += "_"
+	}
+	fmt.Fprintf(buffer, "sort: %s (%s)   filter: %s\n", sortBy, order, filter)
+
+	// help
+	if s.node == "" {
+		fmt.Fprintln(buffer, "[j/k] move  [enter] containers  [</>] sort  [r] reverse  [/] filter  [q] quit")
+	} else {
+		fmt.Fprintln(buffer, "[j/k] move  [esc] nodes  [</>] sort  [r] reverse  [/] filter  [q] quit")
+	}
+
+	// scroll rows to keep cursor on the screen
+	size := height - tuiHeaderLines
+	if size < 1 {
+		size = 1
+	}
+	first := 0
+	if v.cursor >= size {
+		first = v.cursor - size + 1
+	}
+	last := first + size
+	if last > len(rows) {
+		last = len(rows)
+	}
+
+	// table of visible rows, sort column is marked and cursor row is highlighted
+	t := table.NewOutputTable(buffer)
+	for i, h := range header {
+		if i == v.sortBy {
+			h = "*" + h
+		}
+		t.Header = append(t.Header, h)
+	}
+
+	for i := first; i < last; i++ {
+		cells := []string{}
+		for _, cell := range rows[i].cells {
+			if !o.nocolor {
+				if i == v.cursor {
+					util.Highlight(&cell)
+				} else {
+					util.NoHighlight(&cell)
+				}
+			}
+			cells = append(cells, cell)
+		}
+		if i == v.cursor {
+			cells[0] = ">" + cells[0]
+		} else {
+			cells[0] = " " + cells[0]
+		}
+		t.AddRow(cells)
+	}
+
+	if !o.nocolor {
+		for i := range t.Header {
+			util.NoHighlight(&t.Header[i])
+		}
+	}
+	t.Header[0] = " " + t.Header[0]
+
+	t.Print()
+
+	return buffer.String()
+}
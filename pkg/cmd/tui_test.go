@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+var testTuiRows = []tuiRow{
+	{name: "node1", labels: map[string]string{"zone": "a"}, cells: []string{"node1", "10"}, keys: []string{"node1", "10"}},
+	{name: "node2", labels: map[string]string{"zone": "b"}, cells: []string{"node2", "9"}, keys: []string{"node2", "9"}},
+	{name: "node3", labels: map[string]string{"zone": "a"}, cells: []string{"node3", "100"}, keys: []string{"node3", "100"}},
+}
+
+func tuiRowNames(rows []tuiRow) []string {
+	names := []string{}
+	for _, row := range rows {
+		names = append(names, row.name)
+	}
+	return names
+}
+
+func TestFilterTuiRows(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		view        tuiView
+		expected    []string
+	}{
+		{"api order", tuiView{sortBy: -1}, []string{"node1", "node2", "node3"}},
+		{"reverse api order", tuiView{sortBy: -1, reverse: true}, []string{"node3", "node2", "node1"}},
+		{"sort by name", tuiView{sortBy: 0, reverse: true}, []string{"node3", "node2", "node1"}},
+		{"sort by number", tuiView{sortBy: 1}, []string{"node2", "node1", "node3"}},
+		{"filter by name", tuiView{sortBy: -1, filter: "2"}, []string{"node2"}},
+		{"filter by label", tuiView{sortBy: 1, reverse: true, filter: "zone=a"}, []string{"node3", "node1"}},
+		{"no match", tuiView{sortBy: -1, filter: "zone=c"}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := tuiRowNames(filterTuiRows(testTuiRows, &test.view))
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestLessTuiValue(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		a           string
+		b           string
+		expected    bool
+	}{
+		{"numbers", "9", "10", true},
+		{"strings", "node2", "node10", false},
+		{"mixed", "10", "node", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := lessTuiValue(test.a, test.b)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestHandleKey(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		keys        []string
+		quit        bool
+		expected    tuiState
+	}{
+		{
+			"quit",
+			[]string{"q"},
+			true,
+			tuiState{nodeView: tuiView{sortBy: -1}, containerView: tuiView{sortBy: -1}},
+		},
+		{
+			"move cursor within rows",
+			[]string{"k", "j", "\x1b[B", "\x1b[B"},
+			false,
+			tuiState{nodeView: tuiView{cursor: 1, sortBy: -1}, containerView: tuiView{sortBy: -1}},
+		},
+		{
+			"sort and reverse",
+			[]string{">", ">", "\x1b[D", "r", "<", "<"},
+			false,
+			tuiState{nodeView: tuiView{sortBy: -1, reverse: true}, containerView: tuiView{sortBy: -1}},
+		},
+		{
+			"filter",
+			[]string{"/", "n", "o", "x", "\x7f", "\r"},
+			false,
+			tuiState{nodeView: tuiView{sortBy: -1, filter: "no"}, containerView: tuiView{sortBy: -1}},
+		},
+		{
+			"cancel filter",
+			[]string{"/", "n", "\x1b"},
+			false,
+			tuiState{nodeView: tuiView{sortBy: -1}, containerView: tuiView{sortBy: -1}},
+		},
+		{
+			"drill down",
+			[]string{"j", "\r", ">"},
+			false,
+			tuiState{node: "node2", nodeView: tuiView{cursor: 1, sortBy: -1}, containerView: tuiView{sortBy: 0}},
+		},
+		{
+			"back to nodes",
+			[]string{"j", "\r", "\x1b"},
+			false,
+			tuiState{nodeView: tuiView{cursor: 1, sortBy: -1}, containerView: tuiView{sortBy: -1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s := newTuiState()
+
+			quit := false
+			for _, key := range test.keys {
+				quit = s.handleKey(key, testTuiRows[:2])
+			}
+
+			if quit != test.quit {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.quit, quit)
+				return
+			}
+
+			if !reflect.DeepEqual(*s, test.expected) {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expected, *s)
+				return
+			}
+		})
+	}
+}
+
+func TestRenderTui(t *testing.T) {
+
+	header := []string{"NAME", "VALUE"}
+
+	t.Run("cursor and sort column", func(t *testing.T) {
+		o := &FreeOptions{nocolor: true}
+		s := newTuiState()
+		s.nodeView = tuiView{cursor: 1, sortBy: 1}
+
+		expected := strings.Join([]string{
+			"kubectl free tui - 3 nodes",
+			"sort: VALUE (asc)   filter: ",
+			"[j/k] move  [enter] containers  [</>] sort  [r] reverse  [/] filter  [q] quit",
+			" NAME    *VALUE",
+			" node2   9",
+			">node1   10",
+			" node3   100",
+			"",
+		}, "\n")
+
+		actual := o.renderTui(s, header, testTuiRows, 24)
+		if actual != expected {
+			t.Errorf("expected(%q) differ (got: %q)", expected, actual)
+			return
+		}
+	})
+
+	t.Run("scroll to cursor", func(t *testing.T) {
+		o := &FreeOptions{nocolor: true}
+		s := newTuiState()
+		s.node = "node1"
+		s.editing = true
+		s.containerView = tuiView{cursor: 2, sortBy: -1, reverse: true, filter: "node"}
+
+		expected := strings.Join([]string{
+			"kubectl free tui - 3 containers on node1",
+			"sort: - (desc)   filter: node_",
+			"[j/k] move  [esc] nodes  [</>] sort  [r] reverse  [/] filter  [q] quit",
+			" NAME    VALUE",
+			">node1   10",
+			"",
+		}, "\n")
+
+		actual := o.renderTui(s, header, testTuiRows, 5)
+		if actual != expected {
+			t.Errorf("expected(%q) differ (got: %q)", expected, actual)
+			return
+		}
+	})
+}
+
+func TestTuiLoop(t *testing.T) {
+
+	fakeNodeClient := fake.NewSimpleClientset(&testNodes[0])
+	fakePodClient := fake.NewSimpleClientset(&testPods[0])
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:    true,
+		noMetrics:  true,
+		table:      table.NewOutputTable(buffer),
+		nodeClient: fakeNodeClient.CoreV1().Nodes(),
+		podClient:  fakePodClient.CoreV1().Pods("default"),
+	}
+	o.IOStreams.Out = buffer
+	o.prepareFreeTableHeader()
+	o.prepareListTableHeader()
+
+	// refresh nodes, drill into node1 then quit
+	tick := make(chan time.Time)
+	keys := make(chan string)
+	go func() {
+		tick <- time.Now()
+		keys <- "\r"
+		keys <- "q"
+	}()
+
+	height := func() int { return 24 }
+	if err := o.tuiLoop([]string{}, newTuiState(), tick, keys, height); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	frames := strings.Split(buffer.String(), clearScreen)
+	if len(frames) != 5 {
+		t.Errorf("expected(%d) differ (got: %d)", 5, len(frames))
+		return
+	}
+
+	var tests = []struct {
+		description string
+		frame       string
+		expected    string
+	}{
+		{"nodes", frames[1], ">node1   Ready"},
+		{"refreshed nodes", frames[2], ">node1   Ready"},
+		{"containers", frames[3], "containers on node1"},
+		{"container row", frames[3], "container1"},
+		{"cursor on container", frames[3], ">node1 "},
+		{"raw mode newline", frames[3], "\r\n"},
+		{"cleared on quit", frames[4], ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if !strings.Contains(test.frame, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, test.frame)
+				return
+			}
+		})
+	}
+}
+
+func TestListSortKeys(t *testing.T) {
+
+	c := types.Container{
+		Node:      "node1",
+		Namespace: "default",
+		Pod:       "pod1",
+		Name:      "container1",
+		Image:     "alpine:latest",
+		Init:      true,
+		CPU:       types.ContainerResource{Requested: 100, Limited: 200},
+		Memory:    types.ContainerResource{Requested: 1000, Limited: 2000},
+	}
+
+	o := &FreeOptions{
+		nocolor:            true,
+		noMetrics:          true,
+		listContainerImage: true,
+	}
+	o.prepareListTableHeader()
+
+	expected := []string{"node1", "default", "pod1", "", "", "", "container1", "100", "200", "1000", "2000", "alpine:latest"}
+
+	actual := o.listSortKeys(c)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%q) differ (got: %q)", expected, actual)
+		return
+	}
+
+	if len(actual) != len(o.listTableHeaders) {
+		t.Errorf("expected(%d) keys differ (got: %d)", len(o.listTableHeaders), len(actual))
+		return
+	}
+}
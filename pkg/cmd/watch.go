@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// clearScreen moves cursor to top left and clears screen
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+// runWatch redraws resources every interval until SIGINT or SIGTERM
+func (o *FreeOptions) runWatch(args []string) error {
+
+	// watch nodes and pods instead of listing them on each refresh
+	done := make(chan struct{})
+	defer close(done)
+	if err := o.startCollector(args, done); err != nil {
+		return err
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	return o.watchLoop(args, ticker.C, stop)
+}
+
+// watchLoop collects and draws resources on each tick until stop receives a signal
+// A frame is rendered into a buffer then written at once to avoid flicker.
+func (o *FreeOptions) watchLoop(args []string, tick <-chan time.Time, stop <-chan os.Signal) error {
+
+	out := o.table.Output
+	defer func() {
+		o.table.Output = out
+	}()
+
+	// highlight cells changed since the previous frame
+	o.table.Highlight = !o.nocolor
+
+	for {
+		buffer := &bytes.Buffer{}
+		o.table.Output = buffer
+		o.table.Header, o.table.Rows, o.table.Keys = nil, nil, nil
+
+		if err := o.run(args); err != nil {
+			return err
+		}
+
+		fmt.Fprint(out, clearScreen+buffer.String())
+
+		select {
+		case <-stop:
+			return nil
+		case <-tick:
+		}
+	}
+}
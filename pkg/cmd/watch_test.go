@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWatchLoop(t *testing.T) {
+
+	frame := strings.Join([]string{
+		clearScreen + "node1   Ready   1     2     4     25%   50%   1K    2K    4K    25%   50%",
+		"",
+	}, "\n")
+
+	fakeNodeClient := fake.NewSimpleClientset(&testNodes[0])
+	fakePodClient := fake.NewSimpleClientset(&testPods[0])
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:    true,
+		noHeaders:  true,
+		noMetrics:  true,
+		table:      table.NewOutputTable(buffer),
+		nodeClient: fakeNodeClient.CoreV1().Nodes(),
+		podClient:  fakePodClient.CoreV1().Pods("default"),
+	}
+
+	// one tick after the first frame, then stop after the second frame
+	tick := make(chan time.Time)
+	stop := make(chan os.Signal)
+	go func() {
+		tick <- time.Now()
+		stop <- os.Interrupt
+	}()
+
+	if err := o.watchLoop([]string{}, tick, stop); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := frame + frame
+	if buffer.String() != expected {
+		t.Errorf("expected(%q) differ (got: %q)", expected, buffer.String())
+		return
+	}
+
+	if o.table.Output != buffer {
+		t.Errorf("output of table should be restored")
+		return
+	}
+}
@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// whereExpr is a parsed --where expression
+// value returns the value of a key (e.g. "mem.req%") of a node or a container.
+type whereExpr func(value func(key string) sortValue) bool
+
+// whereOperators are operators of --where expression, longer ones first
+var whereOperators = []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "!"}
+
+// whereStringKeys are keys of nodes and containers compared with strings, the others are compared with quantities
+var whereStringKeys = map[string]bool{
+	"name":      true,
+	"status":    true,
+	"node":      true,
+	"namespace": true,
+	"pod":       true,
+	"ip":        true,
+	"container": true,
+	"image":     true,
+}
+
+// whereComparisons are comparison operators of --where expression
+var whereComparisons = map[string]func(c int) bool{
+	"==": func(c int) bool { return c == 0 },
+	"!=": func(c int) bool { return c != 0 },
+	">":  func(c int) bool { return c > 0 },
+	">=": func(c int) bool { return c >= 0 },
+	"<":  func(c int) bool { return c < 0 },
+	"<=": func(c int) bool { return c <= 0 },
+}
+
+// whereParser is a recursive descent parser of --where expression
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = key ( "==" | "!=" | ">" | ">=" | "<" | "<=" ) value
+//
+// Values are quantities (e.g. 500m, 2Gi or 80 for percent), or strings (quoted or not) for whereStringKeys.
+type whereParser struct {
+	tokens []string
+	pos    int
+	isKey  func(key string) bool
+}
+
+// parseWhere parses an expression with keys checked by isKey
+func parseWhere(s string, isKey func(key string) bool) (whereExpr, error) {
+
+	tokens, err := tokenizeWhere(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &whereParser{tokens: tokens, isKey: isKey}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+
+	return expr, nil
+}
+
+// tokenizeWhere splits an expression into keys, values, operators and parentheses
+// Quoted strings keep their quotes to be distinguished from keys.
+func tokenizeWhere(s string) ([]string, error) {
+
+	tokens := []string{}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string: %s", s[i:])
+			}
+			tokens = append(tokens, s[i:i+j+2])
+			i += j + 2
+
+		case strings.IndexByte("=!<>&|", c) >= 0:
+			op := ""
+			for _, o := range whereOperators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unknown operator: %s", s[i:])
+			}
+			tokens = append(tokens, op)
+			i += len(op)
+
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte(" \t()\"'=!<>&|", s[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+// peek returns the current token, empty at the end
+func (p *whereParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// next returns the current token and moves to the next one
+func (p *whereParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// parseOr parses "a || b"
+func (p *whereParser) parseOr() (whereExpr, error) {
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(value func(key string) sortValue) bool {
+			return l(value) || right(value)
+		}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses "a && b"
+func (p *whereParser) parseAnd() (whereExpr, error) {
+
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(value func(key string) sortValue) bool {
+			return l(value) && right(value)
+		}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses "!a", "(a)" or a comparison
+func (p *whereParser) parseUnary() (whereExpr, error) {
+
+	switch p.peek() {
+	case "!":
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(value func(key string) sortValue) bool {
+			return !e(value)
+		}, nil
+
+	case "(":
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return e, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison parses "key op value"
+func (p *whereParser) parseComparison() (whereExpr, error) {
+
+	key := p.next()
+	if key == "" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	if !p.isKey(key) {
+		return nil, fmt.Errorf("unknown key: %s", key)
+	}
+
+	op := p.next()
+	compare, ok := whereComparisons[op]
+	if !ok {
+		return nil, fmt.Errorf("expected comparison operator after %s (got: %q)", key, op)
+	}
+
+	literal := p.next()
+	if literal == "" || literal == "(" || literal == ")" || isWhereOperator(literal) {
+		return nil, fmt.Errorf("expected value after %s %s (got: %q)", key, op, literal)
+	}
+
+	// quoted string
+	if q := literal[0]; q == '"' || q == '\'' {
+		s := literal[1 : len(literal)-1]
+		return func(value func(key string) sortValue) bool {
+			return compare(strings.Compare(value(key).s, s))
+		}, nil
+	}
+
+	// quantity
+	i, ok, err := parseWhereQuantity(key, literal)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return func(value func(key string) sortValue) bool {
+			v := value(key).i
+			switch {
+			case v < i:
+				return compare(-1)
+			case v > i:
+				return compare(1)
+			}
+			return compare(0)
+		}, nil
+	}
+
+	if !whereStringKeys[key] {
+		return nil, fmt.Errorf("%s is not a quantity for %s", literal, key)
+	}
+
+	// bare string (e.g. status == Ready)
+	return func(value func(key string) sortValue) bool {
+		return compare(strings.Compare(value(key).s, literal))
+	}, nil
+}
+
+// parseWhereQuantity returns the raw value of a quantity compared with key, ok is false if literal is not a quantity
+// cpu is in millicores (e.g. 500m or 0.5) and the others must be integers (e.g. bytes or percent).
+func parseWhereQuantity(key, literal string) (int64, bool, error) {
+
+	q, err := resource.ParseQuantity(literal)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	if isCPUKey(key) {
+		return q.MilliValue(), true, nil
+	}
+
+	i, ok := q.AsInt64()
+	if !ok {
+		return 0, false, fmt.Errorf("%s is not an integer for %s", literal, key)
+	}
+
+	return i, true, nil
+}
+
+// isCPUKey returns true if key is cpu in millicores (e.g. "cpu.req", "free.cpu"), not cpu percent
+func isCPUKey(key string) bool {
+	if strings.HasSuffix(key, "%") {
+		return false
+	}
+	return strings.HasPrefix(key, "cpu.") || strings.HasSuffix(key, ".cpu")
+}
+
+// isWhereOperator returns true if token is an operator
+func isWhereOperator(token string) bool {
+	for _, o := range whereOperators {
+		if token == o {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWhere returns an error if --where expression or --names-only is not valid for the output
+func (o *FreeOptions) validateWhere() error {
+
+	if o.where == "" && !o.namesOnly {
+		return nil
+	}
+
+	if o.byNamespace || o.byOwner {
+		return fmt.Errorf("--where and --names-only are not supported with --by-namespace and --by-owner")
+	}
+
+	if o.namesOnly && o.output != "" {
+		return fmt.Errorf("--names-only is supported only with table output (got: %s)", o.output)
+	}
+
+	if o.namesOnly && len(o.groupBy) > 0 {
+		return fmt.Errorf("--names-only is not supported with --group-by")
+	}
+
+	_, err := o.parseWhere()
+	return err
+}
+
+// parseWhere parses --where expression for nodes, or containers with --list
+func (o *FreeOptions) parseWhere() (whereExpr, error) {
+
+	if o.where == "" {
+		return nil, nil
+	}
+
+	expr, err := parseWhere(o.where, o.isKey())
+	if err != nil {
+		return nil, fmt.Errorf("invalid --where expression: %v (keys: %s)", err, o.keyNames())
+	}
+
+	return expr, nil
+}
+
+// whereNodes returns nodes matched with --where expression
+func (o *FreeOptions) whereNodes(nodes []types.Node) ([]types.Node, error) {
+
+	expr, err := o.parseWhere()
+	if expr == nil {
+		return nodes, err
+	}
+
+	matched := []types.Node{}
+	for _, n := range nodes {
+		node := n
+		if expr(func(key string) sortValue { return nodeSortValue(key)(node) }) {
+			matched = append(matched, n)
+		}
+	}
+
+	return matched, nil
+}
+
+// whereContainers returns containers matched with --where expression (--list option)
+func (o *FreeOptions) whereContainers(containers []types.Container) ([]types.Container, error) {
+
+	expr, err := o.parseWhere()
+	if expr == nil {
+		return containers, err
+	}
+
+	matched := []types.Container{}
+	for _, c := range containers {
+		container := c
+		if expr(func(key string) sortValue { return containerSortKeys[key](container) }) {
+			matched = append(matched, c)
+		}
+	}
+
+	return matched, nil
+}
+
+// printNames prints each name once per line (--names-only option)
+// Names can be passed to other commands (e.g. xargs kubectl cordon).
+func (o *FreeOptions) printNames(names []string) {
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		o.table.AddRow([]string{name})
+	}
+
+	o.table.Print()
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/table"
+	"github.com/makocchi-git/kubectl-free/pkg/types"
+
+	v1 "k8s.io/api/core/v1"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTokenizeWhere(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		expression  string
+		expected    []string
+		expectedErr string
+	}{
+		{
+			"comparisons",
+			"mem.req% > 80 || status!=Ready",
+			[]string{"mem.req%", ">", "80", "||", "status", "!=", "Ready"},
+			"",
+		},
+		{
+			"parentheses and quotes",
+			`!(name == "node 1") && nvidia.com/gpu.req>=1`,
+			[]string{"!", "(", "name", "==", `"node 1"`, ")", "&&", "nvidia.com/gpu.req", ">=", "1"},
+			"",
+		},
+		{
+			"unterminated string",
+			"name == 'node1",
+			[]string{},
+			"unterminated string: 'node1",
+		},
+		{
+			"unknown operator",
+			"name = node1",
+			[]string{},
+			"unknown operator: = node1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual, err := tokenizeWhere(test.expression)
+			if err != nil {
+				if err.Error() != test.expectedErr {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expectedErr, err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestParseWhere(t *testing.T) {
+
+	values := map[string]sortValue{
+		"name":     {s: "node1"},
+		"status":   {s: "NotReady"},
+		"mem.req%": {i: 85},
+		"cpu.req":  {i: -1},
+		"cpu.use":  {i: 500},
+		"mem.req":  {i: 2 * 1024 * 1024 * 1024},
+	}
+	value := func(key string) sortValue {
+		return values[key]
+	}
+	isKey := func(key string) bool {
+		_, ok := values[key]
+		return ok
+	}
+
+	var tests = []struct {
+		description string
+		expression  string
+		expected    bool
+		expectedErr string
+	}{
+		{"greater", "mem.req% > 80", true, ""},
+		{"greater or equal", "mem.req% >= 86", false, ""},
+		{"less", "mem.req% < 86", true, ""},
+		{"less or equal", "mem.req% <= 85", true, ""},
+		{"negative", "cpu.req == -1", true, ""},
+		{"millicores", "cpu.use == 500m", true, ""},
+		{"cores", "cpu.use < 1", true, ""},
+		{"decimal cores", "cpu.use >= 0.5", true, ""},
+		{"binary suffix", "mem.req == 2Gi", true, ""},
+		{"decimal suffix", "mem.req > 2G", true, ""},
+		{"not integer", "mem.req% > 80.5", false, "80.5 is not an integer for mem.req%"},
+		{"not quantity", "cpu.req > abc", false, "abc is not a quantity for cpu.req"},
+		{"string", "status != Ready", true, ""},
+		{"quoted string", "name == 'node1'", true, ""},
+		{"string order", `name < "node2"`, true, ""},
+		{"or", "mem.req% > 90 || status != Ready", true, ""},
+		{"and before or", "status == Ready && mem.req% > 90 || name == node1", true, ""},
+		{"parentheses", "status == Ready && (mem.req% > 90 || name == node1)", false, ""},
+		{"not", "!(mem.req% > 80)", false, ""},
+		{"unknown key", "foo > 1", false, "unknown key: foo"},
+		{"missing operator", "mem.req% 80", false, `expected comparison operator after mem.req% (got: "80")`},
+		{"missing value", "mem.req% >", false, `expected value after mem.req% > (got: "")`},
+		{"missing parenthesis", "(mem.req% > 80", false, "missing )"},
+		{"extra token", "mem.req% > 80 80", false, `unexpected "80"`},
+		{"empty", "", false, "unexpected end of expression"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			expr, err := parseWhere(test.expression, isKey)
+			if err != nil {
+				if err.Error() != test.expectedErr {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expectedErr, err)
+				}
+				return
+			}
+
+			if test.expectedErr != "" {
+				t.Errorf("[%s] expected error(%s) but got nothing", test.description, test.expectedErr)
+				return
+			}
+
+			if actual := expr(value); actual != test.expected {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestValidateWhere(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		o           *FreeOptions
+		expected    string
+	}{
+		{
+			"no expression",
+			&FreeOptions{},
+			"",
+		},
+		{
+			"node expression",
+			&FreeOptions{where: "mem.req% > 80 || status != Ready", namesOnly: true},
+			"",
+		},
+		{
+			"container expression",
+			&FreeOptions{where: "cpu.use > 100", list: true},
+			"",
+		},
+		{
+			"node key with list",
+			&FreeOptions{where: "free.mem > 100", list: true},
+			"invalid --where expression: unknown key: free.mem (keys: age, container, ",
+		},
+		{
+			"not quantity",
+			&FreeOptions{where: "pods == foo"},
+			"invalid --where expression: foo is not a quantity for pods",
+		},
+		{
+			"names only with json",
+			&FreeOptions{namesOnly: true, output: "json"},
+			"--names-only is supported only with table output (got: json)",
+		},
+		{
+			"names only with group by",
+			&FreeOptions{namesOnly: true, groupBy: []string{"zone"}},
+			"--names-only is not supported with --group-by",
+		},
+		{
+			"by owner",
+			&FreeOptions{where: "name == node1", byOwner: true},
+			"--where and --names-only are not supported with --by-namespace and --by-owner",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.o.validateWhere()
+			if test.expected == "" {
+				if err != nil {
+					t.Errorf("[%s] unexpected error: %v", test.description, err)
+				}
+				return
+			}
+
+			if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+				return
+			}
+		})
+	}
+}
+
+func TestWhereNodes(t *testing.T) {
+
+	nodes := []types.Node{
+		{Name: "node1", Status: "Ready", Memory: types.NodeResource{RequestedPercent: 85}},
+		{Name: "node2", Status: "Ready", Memory: types.NodeResource{RequestedPercent: 50}, Resources: map[string]types.NodeResource{"nvidia.com/gpu": {LimitedPercent: 100}}},
+		{Name: "node3", Status: "NotReady", Memory: types.NodeResource{RequestedPercent: 10}},
+	}
+
+	var tests = []struct {
+		description string
+		where       string
+		expected    []string
+	}{
+		{"no expression", "", []string{"node1", "node2", "node3"}},
+		{"memory or status", "mem.req% > 80 || status != Ready", []string{"node1", "node3"}},
+		{"no match", "mem.req% > 90", []string{}},
+		{"extended resource limit percent", "nvidia.com/gpu.lim% > 80", []string{"node2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{where: test.where, resources: []string{"nvidia.com/gpu"}}
+
+			items, err := o.whereNodes(nodes)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := []string{}
+			for _, item := range items {
+				actual = append(actual, item.Name)
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestWhereContainers(t *testing.T) {
+
+	containers := []types.Container{
+		{Namespace: "default", Name: "c1", CPU: types.ContainerResource{Requested: 100}},
+		{Namespace: "kube-system", Name: "c2", CPU: types.ContainerResource{Requested: 500}},
+	}
+
+	o := &FreeOptions{list: true, where: "namespace == default || cpu.req > 1"}
+
+	items, err := o.whereContainers(containers)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if len(items) != 1 || items[0].Name != "c1" {
+		t.Errorf("expected(c1) differ (got: %+v)", items)
+		return
+	}
+}
+
+func TestShowNamesOnly(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		list        bool
+		where       string
+		expected    []string
+	}{
+		{
+			"nodes",
+			false,
+			"mem.req% >= 25",
+			[]string{"node1"},
+		},
+		{
+			"pods of containers",
+			true,
+			"cpu.req > 0",
+			[]string{"default/pod1", "default/pod2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fakePodClient := fake.NewSimpleClientset(&testPods[0], &testPods[1])
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				table:     table.NewOutputTable(buffer),
+				nocolor:   true,
+				noMetrics: true,
+				list:      test.list,
+				listAll:   true,
+				where:     test.where,
+				namesOnly: true,
+				podClient: fakePodClient.CoreV1().Pods("default"),
+			}
+
+			nodes := []v1.Node{testNodes[0], testNodes[1]}
+
+			var err error
+			if test.list {
+				err = o.showPodsOnNode(nodes)
+			} else {
+				err = o.showFree(nodes)
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			// order of pods listed from the fake clientset is not stable
+			actual := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+			sort.Strings(actual)
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"github.com/makocchi-git/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Aggregate is sum of effective requested/limit resources of pods on a node
+// cpu is in millicores and others are in bytes or counts.
+type Aggregate struct {
+	Requested       map[v1.ResourceName]int64
+	Limited         map[v1.ResourceName]int64
+	Pods            int64
+	TerminatingPods int64
+	Containers      int64
+}
+
+// NewPodAggregate returns an aggregate of a pod
+func NewPodAggregate(pod v1.Pod) Aggregate {
+
+	a := Aggregate{
+		Requested:  map[v1.ResourceName]int64{},
+		Limited:    map[v1.ResourceName]int64{},
+		Pods:       1,
+		Containers: int64(len(pod.Spec.Containers)),
+	}
+
+	if pod.ObjectMeta.DeletionTimestamp != nil {
+		a.TerminatingPods = 1
+	}
+
+	for _, name := range getPodResourceNames(pod) {
+		a.Requested[name], a.Limited[name] = util.GetPodRequestAndLimit(pod, name)
+	}
+
+	return a
+}
+
+// NewAggregates returns aggregates of pods indexed by node name
+// Pods which are not scheduled or whose phase is not in phases (--count-phases) are not counted.
+func NewAggregates(pods *v1.PodList, phases []string) map[string]Aggregate {
+
+	aggregates := map[string]Aggregate{}
+
+	for _, pod := range pods.Items {
+		if !isCounted(pod, phases) {
+			continue
+		}
+		a := aggregates[pod.Spec.NodeName]
+		a.Add(NewPodAggregate(pod))
+		aggregates[pod.Spec.NodeName] = a
+	}
+
+	return aggregates
+}
+
+// Add adds resources of b to a
+func (a *Aggregate) Add(b Aggregate) {
+	a.add(b, 1)
+}
+
+// Sub subtracts resources of b from a
+func (a *Aggregate) Sub(b Aggregate) {
+	a.add(b, -1)
+}
+
+// add adds resources of b multiplied by sign to a
+func (a *Aggregate) add(b Aggregate, sign int64) {
+
+	if a.Requested == nil {
+		a.Requested = map[v1.ResourceName]int64{}
+	}
+	if a.Limited == nil {
+		a.Limited = map[v1.ResourceName]int64{}
+	}
+
+	for name, v := range b.Requested {
+		a.Requested[name] += sign * v
+	}
+	for name, v := range b.Limited {
+		a.Limited[name] += sign * v
+	}
+
+	a.Pods += sign * b.Pods
+	a.TerminatingPods += sign * b.TerminatingPods
+	a.Containers += sign * b.Containers
+}
+
+// Copy returns a deep copy of the aggregate
+func (a Aggregate) Copy() Aggregate {
+	c := Aggregate{}
+	c.Add(a)
+	return c
+}
+
+// isCounted returns true if the pod is scheduled and its phase is counted
+func isCounted(pod v1.Pod, phases []string) bool {
+	return pod.Spec.NodeName != "" && util.IsCountedPhase(string(pod.Status.Phase), phases)
+}
+
+// getPodResourceNames returns names of resources requested or limited by containers or overhead of a pod
+func getPodResourceNames(pod v1.Pod) []v1.ResourceName {
+
+	names := []v1.ResourceName{}
+	seen := map[v1.ResourceName]bool{}
+
+	add := func(list v1.ResourceList) {
+		for name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, containers := range [][]v1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, c := range containers {
+			add(c.Resources.Requests)
+			add(c.Resources.Limits)
+		}
+	}
+	add(pod.Spec.Overhead)
+
+	return names
+}
@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testPod returns a running pod on node which requests cpu and memory
+func testPod(namespace, name, node string, cpu, mem int64) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1.PodSpec{
+			NodeName: node,
+			Containers: []v1.Container{
+				{
+					Name: "container",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(mem, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+		},
+	}
+}
+
+func TestNewPodAggregate(t *testing.T) {
+
+	now := metav1.Now()
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pod",
+			DeletionTimestamp: &now,
+		},
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{
+				{
+					Name: "init",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(2000, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(100, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			Containers: []v1.Container{
+				{
+					Name: "container1",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(200, resource.DecimalSI),
+						},
+						Limits: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(400, resource.DecimalSI),
+						},
+					},
+				},
+				{
+					Name: "container2",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(200, resource.DecimalSI),
+							"nvidia.com/gpu":  *resource.NewQuantity(1, resource.DecimalSI),
+						},
+						Limits: v1.ResourceList{
+							"nvidia.com/gpu": *resource.NewQuantity(1, resource.DecimalSI),
+						},
+					},
+				},
+			},
+			Overhead: v1.ResourceList{
+				v1.ResourceMemory: *resource.NewQuantity(50, resource.DecimalSI),
+			},
+		},
+	}
+
+	expected := Aggregate{
+		Requested: map[v1.ResourceName]int64{
+			v1.ResourceCPU:    2000,
+			v1.ResourceMemory: 450,
+			"nvidia.com/gpu":  1,
+		},
+		Limited: map[v1.ResourceName]int64{
+			v1.ResourceCPU:    1000,
+			v1.ResourceMemory: 450,
+			"nvidia.com/gpu":  1,
+		},
+		Pods:            1,
+		TerminatingPods: 1,
+		Containers:      2,
+	}
+
+	actual := NewPodAggregate(pod)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%+v) differ (got: %+v)", expected, actual)
+		return
+	}
+}
+
+func TestNewAggregates(t *testing.T) {
+
+	succeeded := testPod("default", "pod4", "node1", 1000, 1000)
+	succeeded.Status.Phase = v1.PodSucceeded
+
+	pods := &v1.PodList{
+		Items: []v1.Pod{
+			*testPod("default", "pod1", "node1", 100, 1000),
+			*testPod("default", "pod2", "node1", 200, 2000),
+			*testPod("default", "pod3", "node2", 300, 3000),
+			*testPod("default", "pending", "", 400, 4000),
+			*succeeded,
+		},
+	}
+
+	var tests = []struct {
+		description string
+		phases      []string
+		expected    map[string]int64
+	}{
+		{
+			"default phases",
+			[]string{},
+			map[string]int64{"node1": 300, "node2": 300},
+		},
+		{
+			"succeeded only",
+			[]string{"Succeeded"},
+			map[string]int64{"node1": 1000},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := map[string]int64{}
+			for node, a := range NewAggregates(pods, test.phases) {
+				actual[node] = a.Requested[v1.ResourceCPU]
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestAggregateAddSub(t *testing.T) {
+
+	a := Aggregate{}
+	a.Add(NewPodAggregate(*testPod("default", "pod1", "node1", 100, 1000)))
+	a.Add(NewPodAggregate(*testPod("default", "pod2", "node1", 200, 2000)))
+
+	c := a.Copy()
+	a.Sub(NewPodAggregate(*testPod("default", "pod1", "node1", 100, 1000)))
+
+	var tests = []struct {
+		description string
+		aggregate   Aggregate
+		expected    []int64
+	}{
+		{"added", c, []int64{300, 3000, 2, 2}},
+		{"subtracted", a, []int64{200, 2000, 1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := []int64{
+				test.aggregate.Requested[v1.ResourceCPU],
+				test.aggregate.Requested[v1.ResourceMemory],
+				test.aggregate.Pods,
+				test.aggregate.Containers,
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+// Package collector keeps resources of nodes and pods up to date for long-running modes (--watch and tui)
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+)
+
+// Collector watches nodes and pods with informers instead of listing them on each refresh
+// Aggregates of nodes are updated incrementally as pods are added, updated and deleted.
+// Metrics can not be watched, so they are polled every interval.
+type Collector struct {
+	nodeInformer cache.SharedIndexInformer
+	podInformer  cache.SharedIndexInformer
+
+	metricsNodeClient metricsv1beta1.NodeMetricsInterface
+	metricsPodClient  metricsv1beta1.PodMetricsInterface
+	interval          time.Duration
+
+	// pod phases to be counted (--count-phases option)
+	phases []string
+
+	mu sync.RWMutex
+
+	// aggregates indexed by node name
+	aggregates map[string]Aggregate
+
+	// counted pods indexed by namespace/name to subtract them on update and delete
+	counted map[string]podEntry
+
+	// last results of metrics poll
+	nodeMetrics    *metricsapiv1beta1.NodeMetricsList
+	nodeMetricsErr error
+	podMetrics     *metricsapiv1beta1.PodMetricsList
+	podMetricsErr  error
+}
+
+// podEntry is the node and the aggregate which a pod added to
+type podEntry struct {
+	node      string
+	aggregate Aggregate
+}
+
+// NewCollector is an instance of Collector
+// Metrics are not polled if metrics clients are nil.
+func NewCollector(
+	nodeClient clientv1.NodeInterface,
+	podClient clientv1.PodInterface,
+	metricsNodeClient metricsv1beta1.NodeMetricsInterface,
+	metricsPodClient metricsv1beta1.PodMetricsInterface,
+	labelSelector string,
+	phases []string,
+	interval time.Duration,
+) *Collector {
+
+	c := &Collector{
+		metricsNodeClient: metricsNodeClient,
+		metricsPodClient:  metricsPodClient,
+		interval:          interval,
+		phases:            phases,
+		aggregates:        map[string]Aggregate{},
+		counted:           map[string]podEntry{},
+	}
+
+	c.nodeInformer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = labelSelector
+				return nodeClient.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = labelSelector
+				return nodeClient.Watch(options)
+			},
+		},
+		&v1.Node{},
+		0,
+		cache.Indexers{},
+	)
+
+	c.podInformer = cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return podClient.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return podClient.Watch(options)
+			},
+		},
+		&v1.Pod{},
+		0,
+		cache.Indexers{},
+	)
+
+	c.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.updatePod(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			c.updatePod(obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.deletePod(obj)
+		},
+	})
+
+	return c
+}
+
+// Start runs informers and metrics poll until stop is closed and waits for the first sync
+func (c *Collector) Start(stop <-chan struct{}) error {
+
+	go c.nodeInformer.Run(stop)
+	go c.podInformer.Run(stop)
+
+	if !cache.WaitForCacheSync(stop, c.nodeInformer.HasSynced, c.podInformer.HasSynced) {
+		return fmt.Errorf("failed to sync nodes and pods")
+	}
+
+	c.resetAggregates()
+
+	if c.metricsNodeClient != nil || c.metricsPodClient != nil {
+		c.pollMetrics()
+		go wait.Until(c.pollMetrics, c.interval, stop)
+	}
+
+	return nil
+}
+
+// updatePod replaces the aggregate of a pod on its node
+func (c *Collector) updatePod(obj interface{}) {
+
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(pod)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removePod(key)
+	c.addPod(key, *pod)
+}
+
+// resetAggregates rebuilds aggregates from pods in the cache
+// Handlers of the initial list may not have run yet when the cache is synced.
+// They can run after this because a pod added again replaces the entry of the same key.
+func (c *Collector) resetAggregates() {
+
+	// the cache is listed under the lock, so events applied after this are not lost
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.aggregates = map[string]Aggregate{}
+	c.counted = map[string]podEntry{}
+
+	for _, pod := range c.GetPods().Items {
+		key, err := cache.MetaNamespaceKeyFunc(&pod)
+		if err != nil {
+			continue
+		}
+		c.addPod(key, pod)
+	}
+}
+
+// addPod adds the aggregate of a pod if it is counted, the caller must hold the lock
+func (c *Collector) addPod(key string, pod v1.Pod) {
+
+	if !isCounted(pod, c.phases) {
+		return
+	}
+
+	entry := podEntry{
+		node:      pod.Spec.NodeName,
+		aggregate: NewPodAggregate(pod),
+	}
+	a := c.aggregates[entry.node]
+	a.Add(entry.aggregate)
+	c.aggregates[entry.node] = a
+	c.counted[key] = entry
+}
+
+// deletePod subtracts the aggregate of a deleted pod from its node
+func (c *Collector) deletePod(obj interface{}) {
+
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removePod(key)
+}
+
+// removePod subtracts the aggregate of a counted pod, the caller must hold the lock
+func (c *Collector) removePod(key string) {
+
+	entry, ok := c.counted[key]
+	if !ok {
+		return
+	}
+	delete(c.counted, key)
+
+	a := c.aggregates[entry.node]
+	a.Sub(entry.aggregate)
+	if a.Pods == 0 {
+		delete(c.aggregates, entry.node)
+		return
+	}
+	c.aggregates[entry.node] = a
+}
+
+// pollMetrics lists metrics of nodes and pods
+func (c *Collector) pollMetrics() {
+
+	var nodeMetrics *metricsapiv1beta1.NodeMetricsList
+	var nodeMetricsErr error
+	if c.metricsNodeClient != nil {
+		nodeMetrics, nodeMetricsErr = c.metricsNodeClient.List(metav1.ListOptions{})
+	}
+
+	var podMetrics *metricsapiv1beta1.PodMetricsList
+	var podMetricsErr error
+	if c.metricsPodClient != nil {
+		podMetrics, podMetricsErr = c.metricsPodClient.List(metav1.ListOptions{})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nodeMetrics, c.nodeMetricsErr = nodeMetrics, nodeMetricsErr
+	c.podMetrics, c.podMetricsErr = podMetrics, podMetricsErr
+}
+
+// GetNodes returns nodes sorted by name, or nodes of names if specified
+func (c *Collector) GetNodes(names []string) ([]v1.Node, error) {
+
+	nodes := []v1.Node{}
+	store := c.nodeInformer.GetStore()
+
+	if len(names) > 0 {
+		for _, name := range names {
+			obj, exists, err := store.GetByKey(name)
+			if err != nil {
+				return nodes, fmt.Errorf("failed to get node: %v", err)
+			}
+			if !exists {
+				return nodes, fmt.Errorf("failed to get node: node %q not found", name)
+			}
+			nodes = append(nodes, *obj.(*v1.Node))
+		}
+		return nodes, nil
+	}
+
+	for _, obj := range store.List() {
+		nodes = append(nodes, *obj.(*v1.Node))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ObjectMeta.Name < nodes[j].ObjectMeta.Name
+	})
+
+	return nodes, nil
+}
+
+// GetPods returns pods sorted by namespace and name
+func (c *Collector) GetPods() *v1.PodList {
+
+	pods := &v1.PodList{}
+	for _, obj := range c.podInformer.GetStore().List() {
+		pods.Items = append(pods.Items, *obj.(*v1.Pod))
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		a, b := pods.Items[i].ObjectMeta, pods.Items[j].ObjectMeta
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	return pods
+}
+
+// GetAggregates returns a copy of aggregates indexed by node name
+func (c *Collector) GetAggregates() map[string]Aggregate {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	aggregates := map[string]Aggregate{}
+	for name, a := range c.aggregates {
+		aggregates[name] = a.Copy()
+	}
+
+	return aggregates
+}
+
+// GetNodeMetrics returns the last result of node metrics poll
+func (c *Collector) GetNodeMetrics() (*metricsapiv1beta1.NodeMetricsList, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nodeMetrics, c.nodeMetricsErr
+}
+
+// GetPodMetrics returns the last result of pod metrics poll
+func (c *Collector) GetPodMetrics() (*metricsapiv1beta1.PodMetricsList, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.podMetrics, c.podMetricsErr
+}
@@ -0,0 +1,307 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	fake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// testNode returns a node with name
+func testNode(name string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+}
+
+// watchStarted returns a channel which receives when the client starts watching the resource
+// Events before the watch is started are not delivered by the fake clientset.
+func watchStarted(client *fake.Clientset, resource string) <-chan struct{} {
+
+	started := make(chan struct{}, 1)
+	client.PrependWatchReactor(resource, func(action core.Action) (bool, watch.Interface, error) {
+		w, err := client.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		return true, w, nil
+	})
+
+	return started
+}
+
+// startTestCollector starts a collector with fake clients which is stopped by closing stop
+func startTestCollector(t *testing.T, nodeClient, podClient *fake.Clientset, stop chan struct{}) *Collector {
+
+	started := watchStarted(podClient, "pods")
+
+	fakeMetricsClient := &fakemetrics.Clientset{}
+	fakeMetricsClient.AddReactor("list", "nodes", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &metricsapiv1beta1.NodeMetricsList{Items: []metricsapiv1beta1.NodeMetrics{{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}}}, nil
+	})
+
+	c := NewCollector(
+		nodeClient.CoreV1().Nodes(),
+		podClient.CoreV1().Pods("default"),
+		fakeMetricsClient.MetricsV1beta1().NodeMetricses(),
+		nil,
+		"",
+		[]string{},
+		time.Minute,
+	)
+
+	if err := c.Start(stop); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watch of pods is not started")
+	}
+
+	return c
+}
+
+// cpuByNode returns requested cpu of aggregates indexed by node name
+func cpuByNode(aggregates map[string]Aggregate) map[string]int64 {
+	cpu := map[string]int64{}
+	for node, a := range aggregates {
+		cpu[node] = a.Requested[v1.ResourceCPU]
+	}
+	return cpu
+}
+
+func TestCollectorAggregates(t *testing.T) {
+
+	nodeClient := fake.NewSimpleClientset(testNode("node1"), testNode("node2"))
+	podClient := fake.NewSimpleClientset(testPod("default", "pod1", "node1", 100, 1000))
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c := startTestCollector(t, nodeClient, podClient, stop)
+
+	pods := podClient.CoreV1().Pods("default")
+
+	var tests = []struct {
+		description string
+		apply       func() error
+		expected    map[string]int64
+	}{
+		{
+			"listed",
+			func() error {
+				return nil
+			},
+			map[string]int64{"node1": 100},
+		},
+		{
+			"added",
+			func() error {
+				_, err := pods.Create(testPod("default", "pod2", "node2", 200, 2000))
+				return err
+			},
+			map[string]int64{"node1": 100, "node2": 200},
+		},
+		{
+			"updated",
+			func() error {
+				_, err := pods.Update(testPod("default", "pod1", "node1", 300, 3000))
+				return err
+			},
+			map[string]int64{"node1": 300, "node2": 200},
+		},
+		{
+			"not counted phase",
+			func() error {
+				pod := testPod("default", "pod2", "node2", 200, 2000)
+				pod.Status.Phase = v1.PodSucceeded
+				_, err := pods.Update(pod)
+				return err
+			},
+			map[string]int64{"node1": 300},
+		},
+		{
+			"deleted",
+			func() error {
+				return pods.Delete("pod1", &metav1.DeleteOptions{})
+			},
+			map[string]int64{},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.apply(); err != nil {
+			t.Errorf("[%s] unexpected error: %v", test.description, err)
+			return
+		}
+
+		var actual map[string]int64
+		err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+			actual = cpuByNode(c.GetAggregates())
+			return reflect.DeepEqual(actual, test.expected), nil
+		})
+		if err != nil {
+			t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			return
+		}
+	}
+}
+
+func TestCollectorAggregatesAfterStart(t *testing.T) {
+
+	objects := []runtime.Object{}
+	for i := 0; i < 100; i++ {
+		objects = append(objects, testPod("default", fmt.Sprintf("pod%d", i), "node1", 10, 100))
+	}
+
+	nodeClient := fake.NewSimpleClientset(testNode("node1"))
+	podClient := fake.NewSimpleClientset(objects...)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c := startTestCollector(t, nodeClient, podClient, stop)
+
+	// all listed pods are aggregated when Start returns
+	expected := map[string]int64{"node1": 1000}
+	if actual := cpuByNode(c.GetAggregates()); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestCollectorGetNodes(t *testing.T) {
+
+	nodeClient := fake.NewSimpleClientset(testNode("node2"), testNode("node1"))
+	podClient := fake.NewSimpleClientset()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c := startTestCollector(t, nodeClient, podClient, stop)
+
+	var tests = []struct {
+		description string
+		names       []string
+		expected    []string
+		expectedErr string
+	}{
+		{"all nodes", []string{}, []string{"node1", "node2"}, ""},
+		{"node names", []string{"node2"}, []string{"node2"}, ""},
+		{"not found", []string{"node3"}, []string{}, `failed to get node: node "node3" not found`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			nodes, err := c.GetNodes(test.names)
+			if err != nil {
+				if err.Error() != test.expectedErr {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expectedErr, err)
+				}
+				return
+			}
+
+			actual := []string{}
+			for _, node := range nodes {
+				actual = append(actual, node.ObjectMeta.Name)
+			}
+
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestCollectorGetPods(t *testing.T) {
+
+	nodeClient := fake.NewSimpleClientset()
+	podClient := fake.NewSimpleClientset(
+		testPod("default", "pod2", "node1", 100, 1000),
+		testPod("default", "pod1", "", 100, 1000),
+	)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c := startTestCollector(t, nodeClient, podClient, stop)
+
+	expected := []string{"pod1", "pod2"}
+	actual := []string{}
+	for _, pod := range c.GetPods().Items {
+		actual = append(actual, pod.ObjectMeta.Name)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestCollectorGetMetrics(t *testing.T) {
+
+	nodeClient := fake.NewSimpleClientset()
+	podClient := fake.NewSimpleClientset()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	c := startTestCollector(t, nodeClient, podClient, stop)
+
+	t.Run("node metrics", func(t *testing.T) {
+		metrics, err := c.GetNodeMetrics()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if len(metrics.Items) != 1 || metrics.Items[0].ObjectMeta.Name != "node1" {
+			t.Errorf("expected(node1) differ (got: %+v)", metrics.Items)
+			return
+		}
+	})
+
+	t.Run("no pod metrics client", func(t *testing.T) {
+		actual, err := c.GetPodMetrics()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if actual != nil {
+			t.Errorf("expected(nil) differ (got: %+v)", actual)
+			return
+		}
+	})
+
+	t.Run("pod metrics error", func(t *testing.T) {
+		fakeMetricsClient := &fakemetrics.Clientset{}
+		fakeMetricsClient.AddReactor("list", "pods", func(action core.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("metrics not available")
+		})
+
+		c := NewCollector(nodeClient.CoreV1().Nodes(), podClient.CoreV1().Pods(""), nil, fakeMetricsClient.MetricsV1beta1().PodMetricses(""), "", []string{}, time.Minute)
+		c.pollMetrics()
+
+		expected := "metrics not available"
+		if _, err := c.GetPodMetrics(); err == nil || err.Error() != expected {
+			t.Errorf("expected(%s) differ (got: %v)", expected, err)
+			return
+		}
+	})
+}
@@ -77,4 +77,15 @@ const (
 
 	// EmojiPodUnknown is unknown emoji for pod status
 	EmojiPodUnknown = "❓"
+
+	//
+	// API
+	//
+
+	// PodListLimit is number of pods in a page of list requests
+	PodListLimit = 500
+
+	// PodListPerNodeMax is max number of nodes whose pods are listed per node by field selector
+	// Pods are listed once for more nodes.
+	PodListPerNodeMax = 5
 )
@@ -0,0 +1,325 @@
+// Package placement checks whether pods fit on nodes by free resources and scheduling constraints
+package placement
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/makocchi-git/kubectl-free/pkg/collector"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+)
+
+const (
+	// taintNodeUnschedulable is the taint of cordoned nodes tolerated by pods like DaemonSet pods
+	taintNodeUnschedulable = "node.kubernetes.io/unschedulable"
+
+	// hostnameTopologyKey is the topology key of pod anti-affinity to spread pods one per node
+	hostnameTopologyKey = "kubernetes.io/hostname"
+)
+
+// PodRequests returns effective requests of a pod with a slot of "pods"
+// cpu is in millicores and others are in bytes or counts.
+func PodRequests(pod v1.Pod) map[v1.ResourceName]int64 {
+
+	requests := map[v1.ResourceName]int64{v1.ResourcePods: 1}
+
+	for name, v := range collector.NewPodAggregate(pod).Requested {
+		if v > 0 {
+			requests[name] = v
+		}
+	}
+
+	return requests
+}
+
+// Copies returns how many copies of requests fit in free resources
+func Copies(free, requests map[v1.ResourceName]int64) int64 {
+
+	copies := int64(-1)
+
+	for name, r := range requests {
+		if r <= 0 {
+			continue
+		}
+
+		n := int64(0)
+		if free[name] > 0 {
+			n = free[name] / r
+		}
+
+		if copies < 0 || n < copies {
+			copies = n
+		}
+	}
+
+	// nothing is requested
+	if copies < 0 {
+		return 0
+	}
+
+	return copies
+}
+
+// Insufficient returns sorted names of resources whose free is less than requested
+func Insufficient(free, requests map[v1.ResourceName]int64) []v1.ResourceName {
+
+	names := []v1.ResourceName{}
+
+	for name, r := range requests {
+		if r > 0 && free[name] < r {
+			names = append(names, name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	return names
+}
+
+// Shortage returns how much of each resource is short to fit requests in free resources
+func Shortage(free, requests map[v1.ResourceName]int64) map[v1.ResourceName]int64 {
+
+	short := map[v1.ResourceName]int64{}
+
+	for name, r := range requests {
+		if r > 0 && free[name] < r {
+			short[name] = r - free[name]
+		}
+	}
+
+	return short
+}
+
+// Unschedulable returns reasons why the pod can not be scheduled on the node regardless of free resources
+// Readiness, cordon, taints, nodeSelector and required node affinity are checked as the scheduler does.
+// Inter-pod affinity, host ports and volumes are not checked.
+func Unschedulable(node v1.Node, spec v1.PodSpec) []string {
+
+	reasons := []string{}
+
+	if !isReady(node) {
+		reasons = append(reasons, "not ready")
+	}
+
+	if node.Spec.Unschedulable {
+		taint := v1.Taint{Key: taintNodeUnschedulable, Effect: v1.TaintEffectNoSchedule}
+		if !tolerates(spec.Tolerations, taint) {
+			reasons = append(reasons, "unschedulable")
+		}
+	}
+
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		if !tolerates(spec.Tolerations, taint) {
+			reasons = append(reasons, fmt.Sprintf("taint %s", taint.ToString()))
+		}
+	}
+
+	nodeLabels := labels.Set(node.ObjectMeta.Labels)
+
+	if !labels.SelectorFromSet(spec.NodeSelector).Matches(nodeLabels) {
+		reasons = append(reasons, "node selector")
+	}
+
+	if a := spec.Affinity; a != nil && a.NodeAffinity != nil && a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		terms := a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		nodeFields := fields.Set{"metadata.name": node.ObjectMeta.Name}
+		if !v1helper.MatchNodeSelectorTerms(terms, nodeLabels, nodeFields) {
+			reasons = append(reasons, "node affinity")
+		}
+	}
+
+	return reasons
+}
+
+// isReady returns true if Ready condition of the node is True
+func isReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// tolerates returns true if any of tolerations tolerates the taint
+func tolerates(tolerations []v1.Toleration, taint v1.Taint) bool {
+	for _, t := range tolerations {
+		if t.ToleratesTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// AntiAffinitySelectors returns label selectors of required pod anti-affinity per node (topology key kubernetes.io/hostname)
+// Pods matched with any of them in the same namespace can not be co-located with the pod.
+func AntiAffinitySelectors(spec v1.PodSpec) ([]labels.Selector, error) {
+
+	selectors := []labels.Selector{}
+
+	if spec.Affinity == nil || spec.Affinity.PodAntiAffinity == nil {
+		return selectors, nil
+	}
+
+	for _, term := range spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey != hostnameTopologyKey {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+
+	return selectors, nil
+}
+
+// MatchesAny returns true if labels are matched with any of selectors
+func MatchesAny(selectors []labels.Selector, l map[string]string) bool {
+	for _, selector := range selectors {
+		if selector.Matches(labels.Set(l)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Node is a node to place pods on with its free resources and pods
+type Node struct {
+	Node v1.Node
+
+	// Free is allocatable - requested with free slots of "pods"
+	Free map[v1.ResourceName]int64
+
+	// Pods are pods on the node to check pod anti-affinity
+	Pods []v1.Pod
+}
+
+// Placement is the node a pod is placed on
+// Node is empty if the pod does not fit, and Reasons has why nodes can not host it with number of the nodes.
+type Placement struct {
+	Pod     v1.Pod
+	Node    string
+	Reasons map[string]int
+}
+
+// Reasons returns why the node can not host the pod, empty if it can
+func (n Node) Reasons(pod v1.Pod, requests map[v1.ResourceName]int64) []string {
+
+	reasons := n.Constraints(pod)
+
+	for _, name := range Insufficient(n.Free, requests) {
+		reasons = append(reasons, "insufficient "+string(name))
+	}
+
+	return reasons
+}
+
+// Constraints returns why the node can not host the pod regardless of free resources
+func (n Node) Constraints(pod v1.Pod) []string {
+
+	reasons := Unschedulable(n.Node, pod.Spec)
+
+	if n.conflicts(pod) {
+		reasons = append(reasons, "pod anti-affinity")
+	}
+
+	return reasons
+}
+
+// conflicts returns true if pods on the node are repelled by pod anti-affinity of the pod
+func (n Node) conflicts(pod v1.Pod) bool {
+
+	selectors, err := AntiAffinitySelectors(pod.Spec)
+	if err != nil || len(selectors) == 0 {
+		return false
+	}
+
+	for _, p := range n.Pods {
+		if p.ObjectMeta.Namespace == pod.ObjectMeta.Namespace && MatchesAny(selectors, p.ObjectMeta.Labels) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeepCopy returns a copy of the node whose free resources and pods can be changed independently
+func (n Node) DeepCopy() Node {
+
+	free := map[v1.ResourceName]int64{}
+	for name, v := range n.Free {
+		free[name] = v
+	}
+
+	return Node{
+		Node: n.Node,
+		Free: free,
+		Pods: append([]v1.Pod{}, n.Pods...),
+	}
+}
+
+// add subtracts requests of the pod from free resources of the node
+func (n *Node) add(pod v1.Pod, requests map[v1.ResourceName]int64) {
+	for name, v := range requests {
+		n.Free[name] -= v
+	}
+	n.Pods = append(n.Pods, pod)
+}
+
+// Place places pods on nodes first-fit-decreasing and returns placements in the order of placing
+// Pods are placed in decreasing order of cpu and memory requests on the first node which can host them,
+// and free resources and pods of nodes are updated by placed pods.
+func Place(pods []v1.Pod, nodes []Node) []Placement {
+
+	requests := []map[v1.ResourceName]int64{}
+	order := []int{}
+	for i, pod := range pods {
+		requests = append(requests, PodRequests(pod))
+		order = append(order, i)
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		ra, rb := requests[order[a]], requests[order[b]]
+		if ra[v1.ResourceCPU] != rb[v1.ResourceCPU] {
+			return ra[v1.ResourceCPU] > rb[v1.ResourceCPU]
+		}
+		return ra[v1.ResourceMemory] > rb[v1.ResourceMemory]
+	})
+
+	placements := []Placement{}
+
+	for _, i := range order {
+		p := Placement{Pod: pods[i]}
+		reasons := map[string]int{}
+
+		for j := range nodes {
+			r := nodes[j].Reasons(pods[i], requests[i])
+			if len(r) == 0 {
+				nodes[j].add(pods[i], requests[i])
+				p.Node = nodes[j].Node.ObjectMeta.Name
+				break
+			}
+			for _, reason := range r {
+				reasons[reason]++
+			}
+		}
+
+		if p.Node == "" {
+			p.Reasons = reasons
+		}
+
+		placements = append(placements, p)
+	}
+
+	return placements
+}
@@ -0,0 +1,385 @@
+package placement
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testNode returns a ready node with labels and taints
+func testNode(name string, labels map[string]string, taints ...v1.Taint) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: v1.NodeSpec{
+			Taints: taints,
+		},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{
+					Type:   v1.NodeReady,
+					Status: v1.ConditionTrue,
+				},
+			},
+		},
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+
+	pod := v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: "c1",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.DecimalSI),
+							v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI),
+						},
+					},
+				},
+				{
+					Name: "c2",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							"nvidia.com/gpu": *resource.NewQuantity(1, resource.DecimalSI),
+						},
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:   *resource.NewMilliQuantity(250, resource.DecimalSI),
+							"nvidia.com/gpu": *resource.NewQuantity(1, resource.DecimalSI),
+						},
+					},
+				},
+				{
+					Name: "limit only",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							v1.ResourceEphemeralStorage: *resource.NewQuantity(0, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := map[v1.ResourceName]int64{
+		v1.ResourcePods:   1,
+		v1.ResourceCPU:    750,
+		v1.ResourceMemory: 1000,
+		"nvidia.com/gpu":  1,
+	}
+
+	actual := PodRequests(pod)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestCopies(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		free        map[v1.ResourceName]int64
+		requests    map[v1.ResourceName]int64
+		expected    int64
+	}{
+		{
+			"limited by cpu",
+			map[v1.ResourceName]int64{v1.ResourceCPU: 2500, v1.ResourceMemory: 8000, v1.ResourcePods: 10},
+			map[v1.ResourceName]int64{v1.ResourceCPU: 1000, v1.ResourceMemory: 1000, v1.ResourcePods: 1},
+			2,
+		},
+		{
+			"limited by pods",
+			map[v1.ResourceName]int64{v1.ResourceCPU: 8000, v1.ResourcePods: 1},
+			map[v1.ResourceName]int64{v1.ResourceCPU: 1000, v1.ResourcePods: 1},
+			1,
+		},
+		{
+			"over committed",
+			map[v1.ResourceName]int64{v1.ResourceCPU: -500, v1.ResourcePods: 10},
+			map[v1.ResourceName]int64{v1.ResourceCPU: 100, v1.ResourcePods: 1},
+			0,
+		},
+		{
+			"missing resource",
+			map[v1.ResourceName]int64{v1.ResourceCPU: 8000, v1.ResourcePods: 10},
+			map[v1.ResourceName]int64{"nvidia.com/gpu": 1, v1.ResourcePods: 1},
+			0,
+		},
+		{
+			"nothing requested",
+			map[v1.ResourceName]int64{v1.ResourceCPU: 8000},
+			map[v1.ResourceName]int64{},
+			0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := Copies(test.free, test.requests)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestInsufficient(t *testing.T) {
+
+	free := map[v1.ResourceName]int64{v1.ResourceCPU: 500, v1.ResourceMemory: 1000, v1.ResourcePods: 0}
+	requests := map[v1.ResourceName]int64{v1.ResourceCPU: 1000, v1.ResourceMemory: 1000, v1.ResourcePods: 1, "nvidia.com/gpu": 1}
+
+	expected := []v1.ResourceName{v1.ResourceCPU, "nvidia.com/gpu", v1.ResourcePods}
+
+	actual := Insufficient(free, requests)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestShortage(t *testing.T) {
+
+	free := map[v1.ResourceName]int64{v1.ResourceCPU: 500, v1.ResourceMemory: -1000, v1.ResourcePods: 10}
+	requests := map[v1.ResourceName]int64{v1.ResourceCPU: 1000, v1.ResourceMemory: 1000, v1.ResourcePods: 1, "nvidia.com/gpu": 1}
+
+	expected := map[v1.ResourceName]int64{v1.ResourceCPU: 500, v1.ResourceMemory: 2000, "nvidia.com/gpu": 1}
+
+	actual := Shortage(free, requests)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+}
+
+func TestUnschedulable(t *testing.T) {
+
+	gpuTaint := v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}
+	preferTaint := v1.Taint{Key: "spot", Effect: v1.TaintEffectPreferNoSchedule}
+
+	notReady := testNode("node1", nil)
+	notReady.Status.Conditions[0].Status = v1.ConditionUnknown
+
+	cordoned := testNode("node1", nil)
+	cordoned.Spec.Unschedulable = true
+
+	affinity := &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		node        v1.Node
+		spec        v1.PodSpec
+		expected    []string
+	}{
+		{"schedulable", testNode("node1", nil, preferTaint), v1.PodSpec{}, []string{}},
+		{"not ready", notReady, v1.PodSpec{}, []string{"not ready"}},
+		{"cordoned", cordoned, v1.PodSpec{}, []string{"unschedulable"}},
+		{
+			"cordoned but tolerated",
+			cordoned,
+			v1.PodSpec{Tolerations: []v1.Toleration{{Key: taintNodeUnschedulable, Operator: v1.TolerationOpExists}}},
+			[]string{},
+		},
+		{"taint", testNode("node1", nil, gpuTaint), v1.PodSpec{}, []string{"taint dedicated=gpu:NoSchedule"}},
+		{
+			"tolerated taint",
+			testNode("node1", nil, gpuTaint),
+			v1.PodSpec{Tolerations: []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu"}}},
+			[]string{},
+		},
+		{
+			"node selector",
+			testNode("node1", map[string]string{"pool": "general"}),
+			v1.PodSpec{NodeSelector: map[string]string{"pool": "gpu"}},
+			[]string{"node selector"},
+		},
+		{
+			"node selector matched",
+			testNode("node1", map[string]string{"pool": "gpu"}),
+			v1.PodSpec{NodeSelector: map[string]string{"pool": "gpu"}},
+			[]string{},
+		},
+		{"node affinity", testNode("node1", map[string]string{"zone": "c"}), v1.PodSpec{Affinity: affinity}, []string{"node affinity"}},
+		{"node affinity matched", testNode("node1", map[string]string{"zone": "b"}), v1.PodSpec{Affinity: affinity}, []string{}},
+		{
+			"all reasons",
+			testNode("node1", nil, gpuTaint),
+			v1.PodSpec{NodeSelector: map[string]string{"pool": "gpu"}, Affinity: affinity},
+			[]string{"taint dedicated=gpu:NoSchedule", "node selector", "node affinity"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := Unschedulable(test.node, test.spec)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestAntiAffinitySelectors(t *testing.T) {
+
+	term := func(topologyKey string, matchLabels map[string]string) v1.PodAffinityTerm {
+		return v1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: matchLabels},
+			TopologyKey:   topologyKey,
+		}
+	}
+
+	spec := v1.PodSpec{
+		Affinity: &v1.Affinity{
+			PodAntiAffinity: &v1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+					term(hostnameTopologyKey, map[string]string{"app": "api"}),
+					term("topology.kubernetes.io/zone", map[string]string{"app": "db"}),
+				},
+			},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		spec        v1.PodSpec
+		labels      map[string]string
+		expected    bool
+	}{
+		{"matched", spec, map[string]string{"app": "api", "tier": "web"}, true},
+		{"other app", spec, map[string]string{"app": "web"}, false},
+		{"zone topology is ignored", spec, map[string]string{"app": "db"}, false},
+		{"no affinity", v1.PodSpec{}, map[string]string{"app": "api"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			selectors, err := AntiAffinitySelectors(test.spec)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := MatchesAny(selectors, test.labels)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestPlace(t *testing.T) {
+
+	pod := func(name string, cpu int64) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": name}},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Name: name,
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU: *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// db repels itself and can not be co-located with db on node2
+	db := pod("db", 1000)
+	db.Spec.Affinity = &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					TopologyKey:   hostnameTopologyKey,
+				},
+			},
+		},
+	}
+
+	nodes := []Node{
+		{
+			Node: testNode("node1", nil),
+			Free: map[v1.ResourceName]int64{v1.ResourceCPU: 2000, v1.ResourcePods: 10},
+		},
+		{
+			Node: testNode("node2", nil),
+			Free: map[v1.ResourceName]int64{v1.ResourceCPU: 4000, v1.ResourcePods: 10},
+			Pods: []v1.Pod{pod("db", 1000)},
+		},
+		{
+			Node: testNode("node3", nil, v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}),
+			Free: map[v1.ResourceName]int64{v1.ResourceCPU: 8000, v1.ResourcePods: 10},
+		},
+	}
+
+	pods := []v1.Pod{pod("small", 500), pod("large", 3000), db, pod("huge", 5000), pod("medium", 1500)}
+
+	// placed in decreasing order of cpu
+	expected := []Placement{
+		{Pod: pods[3], Reasons: map[string]int{"insufficient cpu": 2, "taint dedicated=gpu:NoSchedule": 1}},
+		{Pod: pods[1], Node: "node2"},
+		{Pod: pods[4], Node: "node1"},
+		{Pod: pods[2], Reasons: map[string]int{"insufficient cpu": 1, "pod anti-affinity": 1, "taint dedicated=gpu:NoSchedule": 1}},
+		{Pod: pods[0], Node: "node1"},
+	}
+
+	actual := Place(pods, nodes)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+		return
+	}
+
+	if nodes[0].Free[v1.ResourceCPU] != 0 || len(nodes[0].Pods) != 2 {
+		t.Errorf("unexpected node1: %v", nodes[0])
+		return
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+
+	n := Node{
+		Node: testNode("node1", nil),
+		Free: map[v1.ResourceName]int64{v1.ResourceCPU: 1000},
+		Pods: make([]v1.Pod, 0, 2),
+	}
+
+	c := n.DeepCopy()
+	c.add(v1.Pod{}, map[v1.ResourceName]int64{v1.ResourceCPU: 500})
+
+	if n.Free[v1.ResourceCPU] != 1000 || len(n.Pods) != 0 {
+		t.Errorf("original node is changed: %v", n)
+		return
+	}
+
+	if c.Free[v1.ResourceCPU] != 500 || len(c.Pods) != 1 {
+		t.Errorf("unexpected copy: %v", c)
+		return
+	}
+}
@@ -1,6 +1,7 @@
 package table
 
 import (
+	"encoding/csv"
 	"fmt"
 	"io"
 
@@ -12,8 +13,15 @@ import (
 // OutputTable is struct of tables for outputs
 type OutputTable struct {
 	Header []string
-	Rows   []string
+	Rows   [][]string
 	Output io.Writer
+
+	// Keys identify rows across prints for Highlight, the first cell is used if a key is empty
+	Keys []string
+
+	// Highlight shows cells changed since the previous Print in reverse video (--watch option)
+	Highlight bool
+	previous  map[string][]string
 }
 
 // NewOutputTable is an instance of OutputTable
@@ -29,13 +37,22 @@ func (t *OutputTable) Print() {
 	// get printer
 	printer := printers.GetNewTabWriter(t.Output)
 
+	header, rows := t.Header, []string{}
+	if t.Highlight {
+		header, rows = t.highlight()
+	} else {
+		for _, row := range t.Rows {
+			rows = append(rows, util.JoinTab(row))
+		}
+	}
+
 	// write header
-	if len(t.Header) > 0 {
-		fmt.Fprintln(printer, util.JoinTab(t.Header))
+	if len(header) > 0 {
+		fmt.Fprintln(printer, util.JoinTab(header))
 	}
 
 	// write rows
-	for _, row := range t.Rows {
+	for _, row := range rows {
 		fmt.Fprintln(printer, row)
 	}
 
@@ -43,7 +60,92 @@ func (t *OutputTable) Print() {
 	printer.Flush()
 }
 
+// highlight returns header and rows whose cells changed since the previous call are highlighted
+// Every cell gets escape codes of the same length to keep columns aligned.
+// Rows are matched by their key (or the first cell, e.g. node name) and its occurrence.
+func (t *OutputTable) highlight() ([]string, []string) {
+
+	header := []string{}
+	for _, h := range t.Header {
+		util.NoHighlight(&h)
+		header = append(header, h)
+	}
+
+	current := map[string][]string{}
+	occurrence := map[string]int{}
+	rows := []string{}
+
+	for r, cells := range t.Rows {
+		id := cells[0]
+		if r < len(t.Keys) && t.Keys[r] != "" {
+			id = t.Keys[r]
+		}
+		key := fmt.Sprintf("%s#%d", id, occurrence[id])
+		occurrence[id]++
+		current[key] = cells
+
+		prev, ok := t.previous[key]
+		highlighted := []string{}
+		for i, cell := range cells {
+			c := cell
+			if t.previous != nil && (!ok || i >= len(prev) || prev[i] != cell) {
+				util.Highlight(&c)
+			} else {
+				util.NoHighlight(&c)
+			}
+			highlighted = append(highlighted, c)
+		}
+		rows = append(rows, util.JoinTab(highlighted))
+	}
+
+	t.previous = current
+
+	return header, rows
+}
+
 // AddRow adds row to table
 func (t *OutputTable) AddRow(s []string) {
-	t.Rows = append(t.Rows, util.JoinTab(s))
+	t.AddRowWithKey("", s)
+}
+
+// AddRowWithKey adds row to table with the key which identifies the row across prints (--watch option)
+func (t *OutputTable) AddRowWithKey(key string, s []string) {
+	t.Rows = append(t.Rows, s)
+	t.Keys = append(t.Keys, key)
+}
+
+// PrintCSV shows table output as comma separated values
+func (t *OutputTable) PrintCSV() error {
+	return t.printDelimited(',')
+}
+
+// PrintTSV shows table output as tab separated values
+func (t *OutputTable) PrintTSV() error {
+	return t.printDelimited('\t')
+}
+
+// printDelimited shows table output separated by delimiter with quoting
+func (t *OutputTable) printDelimited(delimiter rune) error {
+
+	w := csv.NewWriter(t.Output)
+	w.Comma = delimiter
+
+	// write header
+	if len(t.Header) > 0 {
+		if err := w.Write(t.Header); err != nil {
+			return err
+		}
+	}
+
+	// write rows
+	for _, row := range t.Rows {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	// finish
+	w.Flush()
+
+	return w.Error()
 }
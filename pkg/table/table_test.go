@@ -5,6 +5,8 @@ import (
 	"os"
 	"reflect"
 	"testing"
+
+	"github.com/makocchi-git/kubectl-free/pkg/util"
 )
 
 func TestNewOutputTable(t *testing.T) {
@@ -27,11 +29,11 @@ func TestPrint(t *testing.T) {
 
 	var tests = []struct {
 		description string
-		rows        []string
+		rows        [][]string
 		expected    string
 	}{
-		{"1 row", []string{"1\t2"}, "a     b\n1     2\n"},
-		{"2 rows", []string{"1\t2", "3\t4"}, "a     b\n1     2\n3     4\n"},
+		{"1 row", [][]string{{"1", "2"}}, "a     b\n1     2\n"},
+		{"2 rows", [][]string{{"1", "2"}, {"3", "4"}}, "a     b\n1     2\n3     4\n"},
 	}
 
 	for _, test := range tests {
@@ -60,20 +62,185 @@ func TestPrint(t *testing.T) {
 	}
 }
 
+func TestHighlight(t *testing.T) {
+
+	// h returns highlighted cell, n returns not highlighted cell
+	h := func(s string) string {
+		util.Highlight(&s)
+		return s
+	}
+	n := func(s string) string {
+		util.NoHighlight(&s)
+		return s
+	}
+
+	table := &OutputTable{Header: []string{"a", "b"}}
+
+	var tests = []struct {
+		description    string
+		rows           [][]string
+		expectedHeader []string
+		expectedRows   []string
+	}{
+		{
+			"first print",
+			[][]string{{"node1", "1"}, {"node2", "2"}},
+			[]string{n("a"), n("b")},
+			[]string{n("node1") + "\t" + n("1"), n("node2") + "\t" + n("2")},
+		},
+		{
+			"changed cell",
+			[][]string{{"node1", "1"}, {"node2", "3"}},
+			[]string{n("a"), n("b")},
+			[]string{n("node1") + "\t" + n("1"), n("node2") + "\t" + h("3")},
+		},
+		{
+			"new row",
+			[][]string{{"node1", "1"}, {"node2", "3"}, {"node3", "1"}},
+			[]string{n("a"), n("b")},
+			[]string{n("node1") + "\t" + n("1"), n("node2") + "\t" + n("3"), h("node3") + "\t" + h("1")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			table.Rows = test.rows
+			header, rows := table.highlight()
+
+			if !reflect.DeepEqual(header, test.expectedHeader) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expectedHeader, header)
+				return
+			}
+			if !reflect.DeepEqual(rows, test.expectedRows) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expectedRows, rows)
+				return
+			}
+		})
+	}
+}
+
+func TestHighlightKeys(t *testing.T) {
+
+	// h returns highlighted cell, n returns not highlighted cell
+	h := func(s string) string {
+		util.Highlight(&s)
+		return s
+	}
+	n := func(s string) string {
+		util.NoHighlight(&s)
+		return s
+	}
+
+	table := &OutputTable{}
+
+	var tests = []struct {
+		description  string
+		keys         []string
+		rows         [][]string
+		expectedRows []string
+	}{
+		{
+			"first print",
+			[]string{"default/pod1/app", "default/pod2/app"},
+			[][]string{{"node1", "pod1"}, {"node1", "pod2"}},
+			[]string{n("node1") + "\t" + n("pod1"), n("node1") + "\t" + n("pod2")},
+		},
+		{
+			"row removed before",
+			[]string{"default/pod2/app"},
+			[][]string{{"node1", "pod2"}},
+			[]string{n("node1") + "\t" + n("pod2")},
+		},
+		{
+			"row added before",
+			[]string{"default/pod0/app", "default/pod2/app"},
+			[][]string{{"node1", "pod0"}, {"node1", "pod2"}},
+			[]string{h("node1") + "\t" + h("pod0"), n("node1") + "\t" + n("pod2")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			table.Rows, table.Keys = nil, nil
+			for i, row := range test.rows {
+				table.AddRowWithKey(test.keys[i], row)
+			}
+
+			_, rows := table.highlight()
+			if !reflect.DeepEqual(rows, test.expectedRows) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expectedRows, rows)
+				return
+			}
+		})
+	}
+}
+
 func TestAddRow(t *testing.T) {
 	table := &OutputTable{}
 	table.AddRow([]string{"1", "2", "3"})
 	table.AddRow([]string{"4", "5", "6"})
 
-	rows := table.Rows
+	expected := [][]string{{"1", "2", "3"}, {"4", "5", "6"}}
 
-	if rows[0] != "1\t2\t3" {
-		t.Errorf("expected(1\t2\t3) differ (got: %s)", rows[0])
+	if !reflect.DeepEqual(table.Rows, expected) {
+		t.Errorf("expected(%q) differ (got: %q)", expected, table.Rows)
 		return
 	}
-	if rows[1] != "4\t5\t6" {
-		t.Errorf("expected(4\t5\t6) differ (got: %s)", rows[1])
+
+	if !reflect.DeepEqual(table.Keys, []string{"", ""}) {
+		t.Errorf("expected(empty keys) differ (got: %q)", table.Keys)
 		return
 	}
 
 }
+
+func TestPrintDelimited(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		header      []string
+		rows        [][]string
+		tsv         bool
+		expected    string
+	}{
+		{"csv", []string{"a", "b"}, [][]string{{"1", "2"}, {"3", "4"}}, false, "a,b\n1,2\n3,4\n"},
+		{"csv without header", []string{}, [][]string{{"1", "2"}}, false, "1,2\n"},
+		{"csv quoting", []string{"a", "b"}, [][]string{{"x,y", "say \"hi\""}}, false, "a,b\n\"x,y\",\"say \"\"hi\"\"\"\n"},
+		{"csv with tab", []string{"a", "b"}, [][]string{{"x\ty", "2"}}, false, "a,b\nx\ty,2\n"},
+		{"tsv", []string{"a", "b"}, [][]string{{"1", "2"}, {"x,y", "4"}}, true, "a\tb\n1\t2\nx,y\t4\n"},
+		{"tsv with tab", []string{"a", "b"}, [][]string{{"x\ty", "2"}}, true, "a\tb\n\"x\ty\"\t2\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			table := &OutputTable{
+				Header: test.header,
+				Rows:   test.rows,
+				Output: buffer,
+			}
+
+			var err error
+			if test.tsv {
+				err = table.PrintTSV()
+			} else {
+				err = table.PrintCSV()
+			}
+
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if buffer.String() != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					buffer.String(),
+				)
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+// Package types has structured documents for json/yaml outputs
+package types
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// APIVersion is apiVersion of output documents
+	APIVersion = "kubectl-free/v1alpha1"
+
+	// KindNodeList is kind of NodeList
+	KindNodeList = "NodeList"
+
+	// KindContainerList is kind of ContainerList
+	KindContainerList = "ContainerList"
+
+	// KindNamespaceList is kind of NamespaceList
+	KindNamespaceList = "NamespaceList"
+
+	// KindWorkloadList is kind of WorkloadList
+	KindWorkloadList = "WorkloadList"
+)
+
+// NodeList is list of Node
+type NodeList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Items []Node `json:"items"`
+
+	// Summary has TOTAL/AVG/MIN/MAX of items (--total/--summary option)
+	Summary []Node `json:"summary,omitempty"`
+}
+
+// Node has requested and allocatable resources of a node
+type Node struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Name   string `json:"name"`
+	Status string `json:"status"`
+
+	// Labels are labels of the node, or label values of the group with --group-by (printed only with --group-by)
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// CPU is in millicores
+	CPU NodeResource `json:"cpu"`
+
+	// Memory is in bytes
+	Memory NodeResource `json:"memory"`
+
+	// EphemeralStorage is in bytes, Used is always 0 and Available is always omitted (not reported by metrics-server)
+	EphemeralStorage NodeResource `json:"ephemeralStorage"`
+
+	Pods            int64 `json:"pods"`
+	PodsAllocatable int64 `json:"podsAllocatable"`
+
+	// TerminatingPods is number of pods being deleted, they are still counted in Pods
+	TerminatingPods int64 `json:"terminatingPods"`
+
+	Containers int64 `json:"containers"`
+
+	// Resources has extended resources (e.g. nvidia.com/gpu, hugepages-2Mi) by name (--resources option)
+	// Hugepages are in bytes and others are in number of devices
+	Resources map[string]NodeResource `json:"resources,omitempty"`
+}
+
+// NodeResource has raw values and percentages of a resource on a node
+type NodeResource struct {
+	Used        int64 `json:"used"`
+	Requested   int64 `json:"requested"`
+	Limited     int64 `json:"limited"`
+	Allocatable int64 `json:"allocatable"`
+
+	// Free is allocatable - requested
+	Free int64 `json:"free"`
+
+	// Available is allocatable - used, nil if metrics are not available
+	Available *int64 `json:"available,omitempty"`
+
+	UsedPercent      int64 `json:"usedPercent"`
+	RequestedPercent int64 `json:"requestedPercent"`
+	LimitedPercent   int64 `json:"limitedPercent"`
+}
+
+// ContainerList is list of Container
+type ContainerList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Items []Container `json:"items"`
+}
+
+// Container has requested resources of a container
+type Container struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Node              string      `json:"node"`
+	Namespace         string      `json:"namespace"`
+	Pod               string      `json:"pod"`
+	PodIP             string      `json:"podIP"`
+	PodStatus         string      `json:"podStatus"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+	Name              string      `json:"name"`
+	Image             string      `json:"image"`
+
+	// Init is true if the container is an init container of the pod
+	Init bool `json:"init,omitempty"`
+
+	// Sidecar is true if the container is a restartable init container of the pod (restartPolicy: Always)
+	Sidecar bool `json:"sidecar,omitempty"`
+
+	// OwnerKind and OwnerName are controller of the pod, empty if the pod has no controller
+	OwnerKind string `json:"ownerKind,omitempty"`
+	OwnerName string `json:"ownerName,omitempty"`
+
+	// CPU is in millicores
+	CPU ContainerResource `json:"cpu"`
+
+	// Memory is in bytes
+	Memory ContainerResource `json:"memory"`
+
+	// EphemeralStorage is in bytes, Used is always 0 (not reported by metrics-server)
+	EphemeralStorage ContainerResource `json:"ephemeralStorage"`
+}
+
+// ContainerResource has raw values of a resource of a container
+type ContainerResource struct {
+	Used      int64 `json:"used"`
+	Requested int64 `json:"requested"`
+	Limited   int64 `json:"limited"`
+}
+
+// NamespaceList is list of Namespace
+type NamespaceList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Items []Namespace `json:"items"`
+}
+
+// Namespace has sum of resources of containers in a namespace
+type Namespace struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Name string `json:"name"`
+
+	// CPU is in millicores
+	CPU AggregateResource `json:"cpu"`
+
+	// Memory is in bytes
+	Memory AggregateResource `json:"memory"`
+
+	Pods       int64 `json:"pods"`
+	Containers int64 `json:"containers"`
+}
+
+// WorkloadList is list of Workload
+type WorkloadList struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Items []Workload `json:"items"`
+}
+
+// Workload has sum of resources of containers in pods owned by a workload
+type Workload struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Namespace string `json:"namespace"`
+
+	// OwnerKind and OwnerName are top level controller of the pods (e.g. Deployment)
+	OwnerKind string `json:"ownerKind"`
+	OwnerName string `json:"ownerName"`
+
+	// CPU is in millicores
+	CPU AggregateResource `json:"cpu"`
+
+	// Memory is in bytes
+	Memory AggregateResource `json:"memory"`
+
+	Replicas   int64 `json:"replicas"`
+	Containers int64 `json:"containers"`
+}
+
+// AggregateResource has sum of raw values and percentages of a resource of containers
+type AggregateResource struct {
+	Used      int64 `json:"used"`
+	Requested int64 `json:"requested"`
+	Limited   int64 `json:"limited"`
+
+	// percentages of total allocatable of nodes
+	UsedPercent      int64 `json:"usedPercent"`
+	RequestedPercent int64 `json:"requestedPercent"`
+	LimitedPercent   int64 `json:"limitedPercent"`
+
+	// UsedRequestedPercent is used * 100 / requested
+	UsedRequestedPercent int64 `json:"usedRequestedPercent"`
+}
+
+// NewNodeList returns NodeList with apiVersion and kind
+func NewNodeList(items []Node) *NodeList {
+	return &NodeList{
+		TypeMeta: metav1.TypeMeta{APIVersion: APIVersion, Kind: KindNodeList},
+		Items:    items,
+	}
+}
+
+// NewContainerList returns ContainerList with apiVersion and kind
+func NewContainerList(items []Container) *ContainerList {
+	return &ContainerList{
+		TypeMeta: metav1.TypeMeta{APIVersion: APIVersion, Kind: KindContainerList},
+		Items:    items,
+	}
+}
+
+// NewNamespaceList returns NamespaceList with apiVersion and kind
+func NewNamespaceList(items []Namespace) *NamespaceList {
+	return &NamespaceList{
+		TypeMeta: metav1.TypeMeta{APIVersion: APIVersion, Kind: KindNamespaceList},
+		Items:    items,
+	}
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *NodeList) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	if in.Items != nil {
+		out.Items = make([]Node, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].deepCopyInto(&out.Items[i])
+		}
+	}
+	if in.Summary != nil {
+		out.Summary = make([]Node, len(in.Summary))
+		for i := range in.Summary {
+			in.Summary[i].deepCopyInto(&out.Summary[i])
+		}
+	}
+	return &out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *Node) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := &Node{}
+	in.deepCopyInto(out)
+	return out
+}
+
+func (in *Node) deepCopyInto(out *Node) {
+	*out = *in
+	if in.Labels != nil {
+		out.Labels = make(map[string]string, len(in.Labels))
+		for k, v := range in.Labels {
+			out.Labels[k] = v
+		}
+	}
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *ContainerList) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	if in.Items != nil {
+		out.Items = make([]Container, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].deepCopyInto(&out.Items[i])
+		}
+	}
+	return &out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *Container) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := &Container{}
+	in.deepCopyInto(out)
+	return out
+}
+
+func (in *Container) deepCopyInto(out *Container) {
+	*out = *in
+	in.CreationTimestamp.DeepCopyInto(&out.CreationTimestamp)
+}
+
+// NewWorkloadList returns WorkloadList with apiVersion and kind
+func NewWorkloadList(items []Workload) *WorkloadList {
+	return &WorkloadList{
+		TypeMeta: metav1.TypeMeta{APIVersion: APIVersion, Kind: KindWorkloadList},
+		Items:    items,
+	}
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *NamespaceList) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	if in.Items != nil {
+		out.Items = make([]Namespace, len(in.Items))
+		copy(out.Items, in.Items)
+	}
+	return &out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *Namespace) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *WorkloadList) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	if in.Items != nil {
+		out.Items = make([]Workload, len(in.Items))
+		copy(out.Items, in.Items)
+	}
+	return &out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *Workload) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
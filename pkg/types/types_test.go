@@ -0,0 +1,114 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewNodeList(t *testing.T) {
+	list := NewNodeList([]Node{{Name: "node1"}})
+
+	if list.APIVersion != APIVersion || list.Kind != KindNodeList {
+		t.Errorf("unexpected type meta: %#v", list.TypeMeta)
+		return
+	}
+
+	if list.GetObjectKind().GroupVersionKind().Empty() {
+		t.Errorf("expected non empty GroupVersionKind")
+		return
+	}
+}
+
+func TestNewContainerList(t *testing.T) {
+	list := NewContainerList([]Container{{Name: "container1"}})
+
+	if list.APIVersion != APIVersion || list.Kind != KindContainerList {
+		t.Errorf("unexpected type meta: %#v", list.TypeMeta)
+		return
+	}
+}
+
+func TestNewNamespaceList(t *testing.T) {
+	list := NewNamespaceList([]Namespace{{Name: "default"}})
+
+	if list.APIVersion != APIVersion || list.Kind != KindNamespaceList {
+		t.Errorf("unexpected type meta: %#v", list.TypeMeta)
+		return
+	}
+}
+
+func TestNewWorkloadList(t *testing.T) {
+	list := NewWorkloadList([]Workload{{OwnerKind: "Deployment", OwnerName: "web"}})
+
+	if list.APIVersion != APIVersion || list.Kind != KindWorkloadList {
+		t.Errorf("unexpected type meta: %#v", list.TypeMeta)
+		return
+	}
+}
+
+func TestDeepCopyObject(t *testing.T) {
+
+	t.Run("NodeList", func(t *testing.T) {
+		in := NewNodeList([]Node{{Name: "node1"}})
+		out := in.DeepCopyObject().(*NodeList)
+
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("expected(%#v) differ (got: %#v)", in, out)
+			return
+		}
+
+		out.Items[0].Name = "node2"
+		if in.Items[0].Name != "node1" {
+			t.Errorf("items should not be shared")
+			return
+		}
+	})
+
+	t.Run("ContainerList", func(t *testing.T) {
+		in := NewContainerList([]Container{{Name: "container1"}})
+		out := in.DeepCopyObject().(*ContainerList)
+
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("expected(%#v) differ (got: %#v)", in, out)
+			return
+		}
+
+		out.Items[0].Name = "container2"
+		if in.Items[0].Name != "container1" {
+			t.Errorf("items should not be shared")
+			return
+		}
+	})
+
+	t.Run("NamespaceList", func(t *testing.T) {
+		in := NewNamespaceList([]Namespace{{Name: "default"}})
+		out := in.DeepCopyObject().(*NamespaceList)
+
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("expected(%#v) differ (got: %#v)", in, out)
+			return
+		}
+
+		out.Items[0].Name = "kube-system"
+		if in.Items[0].Name != "default" {
+			t.Errorf("items should not be shared")
+			return
+		}
+	})
+
+	t.Run("WorkloadList", func(t *testing.T) {
+		in := NewWorkloadList([]Workload{{OwnerKind: "Deployment", OwnerName: "web"}})
+		out := in.DeepCopyObject().(*WorkloadList)
+
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("expected(%#v) differ (got: %#v)", in, out)
+			return
+		}
+
+		out.Items[0].OwnerName = "api"
+		if in.Items[0].OwnerName != "web" {
+			t.Errorf("items should not be shared")
+			return
+		}
+	})
+}
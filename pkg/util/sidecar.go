@@ -0,0 +1,179 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// AnnotationRestartableInitContainers is set by clients of NewPodClient and NewAppsClient to names of
+// restartable init containers (sidecars) of pods and pod templates (comma separated names).
+// Container.RestartPolicy is not available in k8s.io/api of this version, so it is read from the raw response.
+const AnnotationRestartableInitContainers = "kubectl-free.makocchi-git.github.io/restartable-init-containers"
+
+// containerRestartPolicyAlways is restartPolicy of restartable init containers
+const containerRestartPolicyAlways = "Always"
+
+// NewPodClient returns a core/v1 client which records restartable init containers of decoded pods
+func NewPodClient(config *rest.Config) (clientv1.CoreV1Interface, error) {
+
+	client, err := newSidecarRESTClient(config, v1.SchemeGroupVersion, "/api")
+	if err != nil {
+		return nil, err
+	}
+
+	return clientv1.New(client), nil
+}
+
+// NewAppsClient returns an apps/v1 client which records restartable init containers of decoded pod templates
+func NewAppsClient(config *rest.Config) (appsclientv1.AppsV1Interface, error) {
+
+	client, err := newSidecarRESTClient(config, appsv1.SchemeGroupVersion, "/apis")
+	if err != nil {
+		return nil, err
+	}
+
+	return appsclientv1.New(client), nil
+}
+
+// newSidecarRESTClient returns a REST client of the group version whose responses are decoded by sidecarDecoder
+func newSidecarRESTClient(config *rest.Config, gv schema.GroupVersion, apiPath string) (*rest.RESTClient, error) {
+
+	c := rest.CopyConfig(config)
+	c.GroupVersion = &gv
+	c.APIPath = apiPath
+	c.NegotiatedSerializer = sidecarSerializer{serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}}
+	if c.UserAgent == "" {
+		c.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	return rest.RESTClientFor(c)
+}
+
+// sidecarSerializer wraps decoders of responses with sidecarDecoder
+type sidecarSerializer struct {
+	runtime.NegotiatedSerializer
+}
+
+// DecoderToVersion returns a decoder which records restartable init containers of pods and pod templates
+func (s sidecarSerializer) DecoderToVersion(d runtime.Decoder, gv runtime.GroupVersioner) runtime.Decoder {
+	return sidecarDecoder{s.NegotiatedSerializer.DecoderToVersion(d, gv)}
+}
+
+// sidecarDecoder records restartable init containers of pods, pod lists (including watch events) and pod templates
+type sidecarDecoder struct {
+	runtime.Decoder
+}
+
+// rawPod is the part of a pod to find restartable init containers
+type rawPod struct {
+	Spec struct {
+		InitContainers []struct {
+			Name          string `json:"name"`
+			RestartPolicy string `json:"restartPolicy"`
+		} `json:"initContainers"`
+	} `json:"spec"`
+}
+
+// rawWorkload is the part of a workload to find restartable init containers of its pod template
+type rawWorkload struct {
+	Spec struct {
+		Template rawPod `json:"template"`
+	} `json:"spec"`
+}
+
+// Decode decodes data and sets AnnotationRestartableInitContainers of pods and pod templates
+// Responses other than json (e.g. protobuf) are decoded as they are.
+func (d sidecarDecoder) Decode(data []byte, defaults *schema.GroupVersionKind, into runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+
+	obj, gvk, err := d.Decoder.Decode(data, defaults, into)
+	if err != nil {
+		return obj, gvk, err
+	}
+
+	switch o := obj.(type) {
+	case *v1.Pod:
+		SetRestartableInitContainers(o, data)
+	case *v1.PodList:
+		raw := struct {
+			Items []rawPod `json:"items"`
+		}{}
+		if json.Unmarshal(data, &raw) == nil && len(raw.Items) == len(o.Items) {
+			for i := range o.Items {
+				setRestartableInitContainers(&o.Items[i].ObjectMeta, raw.Items[i])
+			}
+		}
+	case *appsv1.Deployment:
+		setTemplateRestartableInitContainers(&o.Spec.Template, data)
+	case *appsv1.StatefulSet:
+		setTemplateRestartableInitContainers(&o.Spec.Template, data)
+	case *appsv1.ReplicaSet:
+		setTemplateRestartableInitContainers(&o.Spec.Template, data)
+	}
+
+	return obj, gvk, nil
+}
+
+// SetRestartableInitContainers sets AnnotationRestartableInitContainers of a pod from its manifest (yaml or json)
+func SetRestartableInitContainers(pod *v1.Pod, manifest []byte) {
+
+	data, err := yaml.ToJSON(manifest)
+	if err != nil {
+		return
+	}
+
+	raw := rawPod{}
+	if json.Unmarshal(data, &raw) == nil {
+		setRestartableInitContainers(&pod.ObjectMeta, raw)
+	}
+}
+
+// setTemplateRestartableInitContainers sets AnnotationRestartableInitContainers of a pod template of a workload
+func setTemplateRestartableInitContainers(template *v1.PodTemplateSpec, data []byte) {
+
+	raw := rawWorkload{}
+	if json.Unmarshal(data, &raw) == nil {
+		setRestartableInitContainers(&template.ObjectMeta, raw.Spec.Template)
+	}
+}
+
+// setRestartableInitContainers sets names of restartable init containers in raw to the annotation
+func setRestartableInitContainers(meta *metav1.ObjectMeta, raw rawPod) {
+
+	names := []string{}
+	for _, c := range raw.Spec.InitContainers {
+		if c.RestartPolicy == containerRestartPolicyAlways {
+			names = append(names, c.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[AnnotationRestartableInitContainers] = strings.Join(names, ",")
+}
+
+// IsRestartableInitContainer returns true if the init container of the pod is a sidecar (restartPolicy: Always)
+func IsRestartableInitContainer(pod v1.Pod, name string) bool {
+	for _, n := range strings.Split(pod.ObjectMeta.Annotations[AnnotationRestartableInitContainers], ",") {
+		if n != "" && n == name {
+			return true
+		}
+	}
+	return false
+}
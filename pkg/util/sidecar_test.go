@@ -0,0 +1,135 @@
+package util
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestSidecarDecoder(t *testing.T) {
+
+	pod := `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"pod1"},"spec":{` +
+		`"initContainers":[{"name":"init"},{"name":"proxy","restartPolicy":"Always"},{"name":"log","restartPolicy":"Always"}],` +
+		`"containers":[{"name":"app"}]}}`
+
+	var tests = []struct {
+		description string
+		data        string
+		expected    []string
+	}{
+		{
+			"pod",
+			pod,
+			[]string{"proxy,log"},
+		},
+		{
+			"pod list",
+			`{"apiVersion":"v1","kind":"PodList","items":[` + pod + `,{"metadata":{"name":"pod2"},"spec":{"containers":[{"name":"app"}]}}]}`,
+			[]string{"proxy,log", ""},
+		},
+		{
+			"deployment",
+			`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"app"},"spec":{"template":{"spec":{` +
+				`"initContainers":[{"name":"proxy","restartPolicy":"Always"}],"containers":[{"name":"app"}]}}}}`,
+			[]string{"proxy"},
+		},
+	}
+
+	d := sidecarDecoder{scheme.Codecs.UniversalDeserializer()}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			obj, _, err := d.Decode([]byte(test.data), nil, nil)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			metas := []metav1.ObjectMeta{}
+			switch o := obj.(type) {
+			case *v1.Pod:
+				metas = append(metas, o.ObjectMeta)
+			case *v1.PodList:
+				for _, p := range o.Items {
+					metas = append(metas, p.ObjectMeta)
+				}
+			case *appsv1.Deployment:
+				metas = append(metas, o.Spec.Template.ObjectMeta)
+			}
+
+			actual := []string{}
+			for _, m := range metas {
+				actual = append(actual, m.Annotations[AnnotationRestartableInitContainers])
+			}
+
+			if len(actual) != len(test.expected) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+				return
+			}
+			for i := range actual {
+				if actual[i] != test.expected[i] {
+					t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestSetRestartableInitContainers(t *testing.T) {
+
+	manifest := `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod1
+spec:
+  initContainers:
+  - name: init
+  - name: proxy
+    restartPolicy: Always
+  containers:
+  - name: app
+`
+
+	pod := v1.Pod{}
+	SetRestartableInitContainers(&pod, []byte(manifest))
+
+	expected := "proxy"
+	if actual := pod.ObjectMeta.Annotations[AnnotationRestartableInitContainers]; actual != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, actual)
+		return
+	}
+}
+
+func TestIsRestartableInitContainer(t *testing.T) {
+
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{AnnotationRestartableInitContainers: "proxy,log"},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		pod         v1.Pod
+		name        string
+		expected    bool
+	}{
+		{"sidecar", pod, "log", true},
+		{"init container", pod, "init", false},
+		{"no annotation", v1.Pod{}, "proxy", false},
+		{"empty name", v1.Pod{}, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if actual := IsRestartableInitContainer(test.pod, test.name); actual != test.expected {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
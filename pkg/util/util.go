@@ -6,6 +6,7 @@ import (
 
 	color "github.com/gookit/color"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
@@ -13,6 +14,14 @@ import (
 	"github.com/makocchi-git/kubectl-free/pkg/constants"
 )
 
+// DefaultCountPhases are pod phases whose resources are counted by default
+// Terminal (Succeeded/Failed) pods are excluded as the scheduler does
+var DefaultCountPhases = []string{
+	string(v1.PodPending),
+	string(v1.PodRunning),
+	string(v1.PodUnknown),
+}
+
 // GetSiUnit defines unit for usage (SI prefix)
 // If multiple options are selected, returns a biggest unit
 func GetSiUnit(b, k, m, g bool) (int64, string) {
@@ -202,70 +211,271 @@ func GetNodeStatus(node v1.Node, emoji bool) (string, error) {
 	}
 
 	if emoji {
-		switch status {
-		case "Ready":
-			status = constants.EmojiReady
-		case "NotReady":
-			status = constants.EmojiNotReady
-		}
+		status = GetNodeStatusEmoji(status)
 	}
 
 	return status, nil
 }
 
-// GetPods returns node objects
-func GetPods(c clientv1.PodInterface, nodeName string) (*v1.PodList, error) {
+// GetNodeStatusEmoji returns emoji for node status
+func GetNodeStatusEmoji(status string) string {
+	switch status {
+	case "Ready":
+		return constants.EmojiReady
+	case "NotReady":
+		return constants.EmojiNotReady
+	}
+
+	return status
+}
 
-	pods, err := c.List(metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
-	if err != nil {
-		return pods, fmt.Errorf("failed to get pods: %s", err)
+// ListPods returns pods matched with the field selector with paginated list requests
+// The list is restarted once if the continue token is expired (410 Gone) while paging.
+func ListPods(c clientv1.PodInterface, fieldSelector string, limit int64) (*v1.PodList, error) {
+
+	pods := &v1.PodList{}
+	opts := metav1.ListOptions{FieldSelector: fieldSelector, Limit: limit}
+	restarted := false
+
+	for {
+		list, err := c.List(opts)
+		if err != nil && opts.Continue != "" && (apierrors.IsResourceExpired(err) || apierrors.IsGone(err)) {
+			if restarted {
+				return pods, fmt.Errorf("failed to list pods: continue token expired again after restarting the list: %s", err)
+			}
+			// pods of the expired snapshot are listed again from the first page
+			pods.Items = nil
+			opts.Continue = ""
+			restarted = true
+			continue
+		}
+		if err != nil {
+			return pods, fmt.Errorf("failed to list pods: %s", err)
+		}
+		pods.Items = append(pods.Items, list.Items...)
+
+		// last page
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
 	}
 
 	return pods, nil
 }
 
-// GetContainerMetrics returns container metrics usage
-func GetContainerMetrics(metrics *metricsapiv1beta1.PodMetricsList, podName, containerName string) (cpu, mem int64) {
+// GetPodsByNode returns pods indexed by node name
+// Pods which are not scheduled to any node are not included
+func GetPodsByNode(pods *v1.PodList) map[string]v1.PodList {
+
+	index := map[string]v1.PodList{}
+
+	for _, pod := range pods.Items {
+		nodeName := pod.Spec.NodeName
+		if nodeName == "" {
+			continue
+		}
+		l := index[nodeName]
+		l.Items = append(l.Items, pod)
+		index[nodeName] = l
+	}
+
+	return index
+}
+
+// GetNodeMetricsByName returns node metrics indexed by node name
+func GetNodeMetricsByName(metrics *metricsapiv1beta1.NodeMetricsList) map[string]metricsapiv1beta1.NodeMetrics {
+
+	index := map[string]metricsapiv1beta1.NodeMetrics{}
+
+	for _, m := range metrics.Items {
+		index[m.ObjectMeta.Name] = m
+	}
+
+	return index
+}
+
+// ContainerMetricsIndex is usage of containers indexed by namespace/pod/container
+type ContainerMetricsIndex map[string]v1.ResourceList
+
+// NewContainerMetricsIndex returns ContainerMetricsIndex of pod metrics
+func NewContainerMetricsIndex(metrics *metricsapiv1beta1.PodMetricsList) ContainerMetricsIndex {
 
-	var c int64
-	var m int64
+	index := ContainerMetricsIndex{}
+
+	if metrics == nil {
+		return index
+	}
 
 	for _, pod := range metrics.Items {
-		if pod.ObjectMeta.Name == podName {
-			for _, container := range pod.Containers {
-				if container.Name == containerName {
-					c = container.Usage.Cpu().MilliValue()
-					m = container.Usage.Memory().Value()
-					break
-				}
-			}
+		for _, container := range pod.Containers {
+			index[containerMetricsKey(pod.ObjectMeta.Namespace, pod.ObjectMeta.Name, container.Name)] = container.Usage
 		}
 	}
 
-	// if no metrics found, return 0 0
-	return c, m
+	return index
 }
 
-// GetPodResources returns sum of requested/limit resources
+// Get returns cpu (millicores) and memory (bytes) usage of a container
+// If no metrics found, return 0 0
+func (i ContainerMetricsIndex) Get(namespace, podName, containerName string) (cpu, mem int64) {
+
+	usage, ok := i[containerMetricsKey(namespace, podName, containerName)]
+	if !ok {
+		return 0, 0
+	}
+
+	return usage.Cpu().MilliValue(), usage.Memory().Value()
+}
+
+// containerMetricsKey returns key of ContainerMetricsIndex
+func containerMetricsKey(namespace, podName, containerName string) string {
+	return namespace + "/" + podName + "/" + containerName
+}
+
+// IsCountedPhase returns true if pod phase is in phases
+// Empty phases means DefaultCountPhases
+func IsCountedPhase(phase string, phases []string) bool {
+	if len(phases) == 0 {
+		phases = DefaultCountPhases
+	}
+
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterPods returns pods whose phase is in phases
+func FilterPods(pods v1.PodList, phases []string) v1.PodList {
+	filtered := v1.PodList{}
+
+	for _, pod := range pods.Items {
+		if IsCountedPhase(string(pod.Status.Phase), phases) {
+			filtered.Items = append(filtered.Items, pod)
+		}
+	}
+
+	return filtered
+}
+
+// GetPodResources returns sum of effective requested/limit resources of pods
+// Pods should be filtered by FilterPods before
 func GetPodResources(pods v1.PodList) (int64, int64, int64, int64) {
 	var rc, rm, lc, lm int64
 
 	for _, pod := range pods.Items {
+		prc, prm, plc, plm := GetPodRequestsAndLimits(pod)
+		rc += prc
+		rm += prm
+		lc += plc
+		lm += plm
+	}
 
-		// skip if pod status is not running
-		if pod.Status.Phase != v1.PodRunning {
-			continue
-		}
+	return rc, rm, lc, lm
+}
+
+// GetPodRequestsAndLimits returns effective requested/limit cpu and memory of a pod
+func GetPodRequestsAndLimits(pod v1.Pod) (int64, int64, int64, int64) {
+	rc, lc := GetPodRequestAndLimit(pod, v1.ResourceCPU)
+	rm, lm := GetPodRequestAndLimit(pod, v1.ResourceMemory)
+	return rc, rm, lc, lm
+}
 
-		for _, container := range pod.Spec.Containers {
-			rc += container.Resources.Requests.Cpu().MilliValue()
-			lc += container.Resources.Limits.Cpu().MilliValue()
-			rm += container.Resources.Requests.Memory().Value()
-			lm += container.Resources.Limits.Memory().Value()
+// GetPodRequestAndLimit returns effective requested/limit value of a resource of a pod as the scheduler computes
+// Init containers run one by one before app containers, so the larger of the largest init container
+// and the sum of app containers is used, then pod overhead (RuntimeClass) is added.
+// Restartable init containers (sidecars) keep running, so they are added to the sum of app containers
+// and to init containers started after them.
+func GetPodRequestAndLimit(pod v1.Pod, name v1.ResourceName) (int64, int64) {
+	var r, l int64
+
+	// app containers
+	for _, container := range pod.Spec.Containers {
+		r += GetResourceValue(container.Resources.Requests, name)
+		l += GetResourceValue(container.Resources.Limits, name)
+	}
+
+	// init containers, sr and sl are sums of sidecars started so far
+	var ir, il, sr, sl int64
+	for _, container := range pod.Spec.InitContainers {
+		cr := GetResourceValue(container.Resources.Requests, name)
+		cl := GetResourceValue(container.Resources.Limits, name)
+
+		if IsRestartableInitContainer(pod, container.Name) {
+			r += cr
+			l += cl
+			sr += cr
+			sl += cl
+			cr, cl = sr, sl
+		} else {
+			cr += sr
+			cl += sl
 		}
+
+		ir = max(ir, cr)
+		il = max(il, cl)
+	}
+	r = max(r, ir)
+	l = max(l, il)
+
+	// pod overhead is added to requests and to limits only if limits are set
+	overhead := GetResourceValue(pod.Spec.Overhead, name)
+	r += overhead
+	if l > 0 {
+		l += overhead
 	}
 
-	return rc, rm, lc, lm
+	return r, l
+}
+
+// GetPodsRequestAndLimit returns sum of effective requested/limit value of a resource of pods
+// Pods should be filtered by FilterPods before
+func GetPodsRequestAndLimit(pods v1.PodList, name v1.ResourceName) (int64, int64) {
+	var r, l int64
+
+	for _, pod := range pods.Items {
+		pr, pl := GetPodRequestAndLimit(pod, name)
+		r += pr
+		l += pl
+	}
+
+	return r, l
+}
+
+// IsStandardResource returns true if the resource has dedicated columns (cpu, memory, pods and ephemeral-storage)
+func IsStandardResource(name v1.ResourceName) bool {
+	switch name {
+	case v1.ResourceCPU, v1.ResourceMemory, v1.ResourcePods, v1.ResourceEphemeralStorage:
+		return true
+	}
+	return false
+}
+
+// GetResourceValue returns value of a resource in the list
+// cpu is in millicores and others are raw values (e.g. bytes)
+func GetResourceValue(list v1.ResourceList, name v1.ResourceName) int64 {
+	q, ok := list[name]
+	if !ok {
+		return 0
+	}
+
+	if name == v1.ResourceCPU {
+		return q.MilliValue()
+	}
+
+	return q.Value()
+}
+
+// max returns larger one of a and b
+func max(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
 }
 
 // GetPodCount returns count of pods
@@ -273,6 +483,17 @@ func GetPodCount(pods v1.PodList) int {
 	return len(pods.Items)
 }
 
+// GetTerminatingPodCount returns count of pods being deleted
+func GetTerminatingPodCount(pods v1.PodList) int {
+	var c int
+	for _, pod := range pods.Items {
+		if pod.ObjectMeta.DeletionTimestamp != nil {
+			c++
+		}
+	}
+	return c
+}
+
 // GetContainerCount returns count of containers
 func GetContainerCount(pods v1.PodList) int {
 	var c int
@@ -311,3 +532,13 @@ func Red(s *string) {
 func Yellow(s *string) {
 	*s = color.FgYellow.Render(*s)
 }
+
+// Highlight shows string in reverse video
+func Highlight(s *string) {
+	*s = color.OpReverse.Render(*s)
+}
+
+// NoHighlight adds dummy escape code of the same length as Highlight
+func NoHighlight(s *string) {
+	*s = color.OpReset.Render(*s)
+}
@@ -2,7 +2,9 @@ package util
 
 import (
 	"bytes"
+	"errors"
 	"strconv"
+	"strings"
 	"testing"
 
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -11,8 +13,11 @@ import (
 
 	color "github.com/gookit/color"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	fake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
@@ -506,17 +511,42 @@ func TestGetNodeStatus(t *testing.T) {
 	}
 }
 
-func TestGetPods(t *testing.T) {
-	fakeClient := fake.NewSimpleClientset(&testPods[0], &testPods[1])
-	fakepod := fakeClient.CoreV1().Pods("")
+func TestGetNodeStatusEmoji(t *testing.T) {
 
-	// get pods
-	t.Run("get pods", func(t *testing.T) {
+	var tests = []struct {
+		description string
+		status      string
+		expected    string
+	}{
+		{"ready", "Ready", "😃"},
+		{"notready", "NotReady", "😭"},
+		{"other", "Unknown", "Unknown"},
+	}
 
-		// FieldSelector on fakeclient doesn't work well
-		// https://github.com/kubernetes/client-go/issues/326
-		pods, err := GetPods(fakepod, "dummy")
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetNodeStatusEmoji(test.status)
+			if actual != test.expected {
+				t.Errorf(
+					"[%s] expected(%s) differ (got: %s)",
+					test.description,
+					test.expected,
+					actual,
+				)
+				return
+			}
+		})
+	}
+}
+
+func TestListPods(t *testing.T) {
+
+	t.Run("list pods", func(t *testing.T) {
 
+		fakeClient := fake.NewSimpleClientset(&testPods[0], &testPods[1])
+		fakepod := fakeClient.CoreV1().Pods("")
+
+		pods, err := ListPods(fakepod, "", 500)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
@@ -524,10 +554,163 @@ func TestGetPods(t *testing.T) {
 
 		l := len(pods.Items)
 		if l != 2 {
-			t.Errorf("[correct node name] expected(2) differ (got: %d)", l)
+			t.Errorf("[list pods] expected(2) differ (got: %d)", l)
+			return
+		}
+	})
+
+	t.Run("list pods with pagination", func(t *testing.T) {
+
+		// fake client doesn't support Limit/Continue, so return pages by reactor
+		pages := []*v1.PodList{
+			{ListMeta: metav1.ListMeta{Continue: "page2"}, Items: []v1.Pod{testPods[0]}},
+			{ListMeta: metav1.ListMeta{Continue: "page3"}, Items: []v1.Pod{testPods[1]}},
+			{Items: []v1.Pod{testPods[2]}},
+		}
+
+		calls := 0
+		fakeClient := fake.NewSimpleClientset()
+		fakeClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			page := pages[calls]
+			calls++
+			return true, page, nil
+		})
+
+		pods, err := ListPods(fakeClient.CoreV1().Pods(""), "", 1)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		if calls != 3 {
+			t.Errorf("[list calls] expected(3) differ (got: %d)", calls)
 			return
 		}
+
+		l := len(pods.Items)
+		if l != 3 {
+			t.Errorf("[list pods with pagination] expected(3) differ (got: %d)", l)
+			return
+		}
+	})
+
+	t.Run("list pods with expired continue token", func(t *testing.T) {
+
+		expired := apierrors.NewGone("continue token expired")
+
+		var tests = []struct {
+			description string
+			errors      []error
+			expected    string
+		}{
+			{"restart", []error{nil, expired, nil, nil}, ""},
+			{"expired again", []error{nil, expired, nil, expired}, "failed to list pods: continue token expired again after restarting the list: continue token expired"},
+		}
+
+		for _, test := range tests {
+
+			// first page, expired second page, then the list is restarted
+			pages := []*v1.PodList{
+				{ListMeta: metav1.ListMeta{Continue: "page2"}, Items: []v1.Pod{testPods[0]}},
+				nil,
+				{ListMeta: metav1.ListMeta{Continue: "page2"}, Items: []v1.Pod{testPods[0]}},
+				{Items: []v1.Pod{testPods[1]}},
+			}
+
+			calls := 0
+			fakeClient := fake.NewSimpleClientset()
+			fakeClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				page, err := pages[calls], test.errors[calls]
+				calls++
+				return true, page, err
+			})
+
+			pods, err := ListPods(fakeClient.CoreV1().Pods(""), "", 1)
+			if test.expected != "" {
+				if err == nil || err.Error() != test.expected {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				continue
+			}
+
+			if l := len(pods.Items); l != 2 {
+				t.Errorf("[%s] expected(2) differ (got: %d)", test.description, l)
+			}
+		}
 	})
+
+	t.Run("list error", func(t *testing.T) {
+
+		fakeClient := fake.NewSimpleClientset()
+		fakeClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("forbidden")
+		})
+
+		_, err := ListPods(fakeClient.CoreV1().Pods(""), "", 500)
+		if err == nil {
+			t.Errorf("expected error but got nil")
+			return
+		}
+
+		expected := "failed to list pods: forbidden"
+		if err.Error() != expected {
+			t.Errorf("expected(%s) differ (got: %s)", expected, err.Error())
+			return
+		}
+	})
+}
+
+func TestGetPodsByNode(t *testing.T) {
+
+	unscheduled := testPods[0].DeepCopy()
+	unscheduled.ObjectMeta.Name = "pod4"
+	unscheduled.Spec.NodeName = ""
+
+	pods := &v1.PodList{
+		Items: []v1.Pod{
+			testPods[0],
+			testPods[1],
+			testPods[2],
+			*unscheduled,
+		},
+	}
+
+	var tests = []struct {
+		description string
+		nodeName    string
+		expected    []string
+	}{
+		{"node1", "node1", []string{"pod1"}},
+		{"node2", "node2", []string{"pod2"}},
+		{"node3", "node3", []string{"pod3"}},
+		{"no pods", "node4", []string{}},
+	}
+
+	index := GetPodsByNode(pods)
+
+	if len(index) != 3 {
+		t.Errorf("[number of nodes] expected(3) differ (got: %d)", len(index))
+		return
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			actual := []string{}
+			for _, pod := range index[test.nodeName].Items {
+				actual = append(actual, pod.ObjectMeta.Name)
+			}
+
+			if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
 }
 
 func TestGetPodResources(t *testing.T) {
@@ -548,7 +731,8 @@ func TestGetPodResources(t *testing.T) {
 	// get pods resource
 	t.Run("get pods resource", func(t *testing.T) {
 
-		rc, rm, lc, lm := GetPodResources(pods)
+		// failed pod3 is excluded by default
+		rc, rm, lc, lm := GetPodResources(FilterPods(pods, DefaultCountPhases))
 
 		// 1000 + 500 + 50
 		if rc != 1550 {
@@ -576,22 +760,303 @@ func TestGetPodResources(t *testing.T) {
 	})
 }
 
-func TestGetContainerMetrics(t *testing.T) {
+func TestGetPodRequestsAndLimits(t *testing.T) {
+
+	// container returns a container with cpu/memory requests and limits
+	container := func(rc, rm, lc, lm int64) v1.Container {
+		c := v1.Container{
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceCPU:    *resource.NewMilliQuantity(rc, resource.DecimalSI),
+					v1.ResourceMemory: *resource.NewQuantity(rm, resource.DecimalSI),
+				},
+				Limits: v1.ResourceList{},
+			},
+		}
+		if lc > 0 {
+			c.Resources.Limits[v1.ResourceCPU] = *resource.NewMilliQuantity(lc, resource.DecimalSI)
+		}
+		if lm > 0 {
+			c.Resources.Limits[v1.ResourceMemory] = *resource.NewQuantity(lm, resource.DecimalSI)
+		}
+		return c
+	}
+
+	// sidecar names a restartable init container
+	sidecar := func(c v1.Container) v1.Container {
+		c.Name = "sidecar"
+		return c
+	}
+	annotations := map[string]string{AnnotationRestartableInitContainers: "sidecar"}
+
+	overhead := v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(10, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(100, resource.DecimalSI),
+	}
+
+	var tests = []struct {
+		description string
+		spec        v1.PodSpec
+		expected    []int64
+	}{
+		{
+			"app containers only",
+			v1.PodSpec{
+				Containers: []v1.Container{container(100, 1000, 200, 2000), container(50, 500, 0, 0)},
+			},
+			[]int64{150, 1500, 200, 2000},
+		},
+		{
+			"init container smaller than app containers",
+			v1.PodSpec{
+				InitContainers: []v1.Container{container(100, 1000, 100, 1000)},
+				Containers:     []v1.Container{container(100, 1000, 200, 2000), container(50, 500, 0, 0)},
+			},
+			[]int64{150, 1500, 200, 2000},
+		},
+		{
+			"init container larger than app containers",
+			v1.PodSpec{
+				InitContainers: []v1.Container{container(500, 200, 1000, 0), container(300, 3000, 0, 3000)},
+				Containers:     []v1.Container{container(100, 1000, 200, 2000), container(50, 500, 0, 0)},
+			},
+			[]int64{500, 3000, 1000, 3000},
+		},
+		{
+			"sidecar added to app containers",
+			v1.PodSpec{
+				InitContainers: []v1.Container{sidecar(container(50, 500, 100, 0))},
+				Containers:     []v1.Container{container(100, 1000, 200, 2000)},
+			},
+			[]int64{150, 1500, 300, 2000},
+		},
+		{
+			"init container after sidecar",
+			v1.PodSpec{
+				InitContainers: []v1.Container{sidecar(container(50, 500, 100, 0)), container(500, 200, 0, 0)},
+				Containers:     []v1.Container{container(100, 1000, 200, 2000)},
+			},
+			[]int64{550, 1500, 300, 2000},
+		},
+		{
+			"init container before sidecar",
+			v1.PodSpec{
+				InitContainers: []v1.Container{container(500, 200, 0, 0), sidecar(container(50, 500, 100, 0))},
+				Containers:     []v1.Container{container(100, 1000, 200, 2000)},
+			},
+			[]int64{500, 1500, 300, 2000},
+		},
+		{
+			"pod overhead",
+			v1.PodSpec{
+				Containers: []v1.Container{container(100, 1000, 200, 2000)},
+				Overhead:   overhead,
+			},
+			[]int64{110, 1100, 210, 2100},
+		},
+		{
+			"pod overhead without limits",
+			v1.PodSpec{
+				Containers: []v1.Container{container(100, 1000, 0, 0)},
+				Overhead:   overhead,
+			},
+			[]int64{110, 1100, 0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}, Spec: test.spec}
+			rc, rm, lc, lm := GetPodRequestsAndLimits(pod)
+			actual := []int64{rc, rm, lc, lm}
+			for i := range actual {
+				if actual[i] != test.expected[i] {
+					t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestGetPodsRequestAndLimit(t *testing.T) {
+
+	pod := func(r, l int64) v1.Pod {
+		return v1.Pod{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceEphemeralStorage: *resource.NewQuantity(r, resource.DecimalSI),
+							},
+							Limits: v1.ResourceList{
+								v1.ResourceEphemeralStorage: *resource.NewQuantity(l, resource.DecimalSI),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	pods := v1.PodList{Items: []v1.Pod{pod(1000, 2000), pod(500, 1000)}}
+
+	r, l := GetPodsRequestAndLimit(pods, v1.ResourceEphemeralStorage)
+	if r != 1500 || l != 3000 {
+		t.Errorf("expected(1500, 3000) differ (got: %d, %d)", r, l)
+		return
+	}
+
+	// resource not requested
+	r, l = GetPodsRequestAndLimit(pods, v1.ResourceMemory)
+	if r != 0 || l != 0 {
+		t.Errorf("expected(0, 0) differ (got: %d, %d)", r, l)
+		return
+	}
+}
+
+func TestIsStandardResource(t *testing.T) {
+
+	var tests = []struct {
+		name     v1.ResourceName
+		expected bool
+	}{
+		{v1.ResourceCPU, true},
+		{v1.ResourceMemory, true},
+		{v1.ResourcePods, true},
+		{v1.ResourceEphemeralStorage, true},
+		{"nvidia.com/gpu", false},
+		{"hugepages-2Mi", false},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.name), func(t *testing.T) {
+			actual := IsStandardResource(test.name)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.name, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestGetResourceValue(t *testing.T) {
+
+	list := v1.ResourceList{
+		v1.ResourceCPU:              *resource.NewMilliQuantity(1500, resource.DecimalSI),
+		v1.ResourceMemory:           *resource.NewQuantity(2048, resource.BinarySI),
+		v1.ResourceEphemeralStorage: *resource.NewQuantity(4096, resource.BinarySI),
+	}
+
+	var tests = []struct {
+		description string
+		name        v1.ResourceName
+		expected    int64
+	}{
+		{"cpu in millicores", v1.ResourceCPU, 1500},
+		{"memory in bytes", v1.ResourceMemory, 2048},
+		{"ephemeral-storage in bytes", v1.ResourceEphemeralStorage, 4096},
+		{"not found", v1.ResourcePods, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetResourceValue(list, test.name)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestGetNodeMetricsByName(t *testing.T) {
+
+	metrics := &metricsapiv1beta1.NodeMetricsList{
+		Items: []metricsapiv1beta1.NodeMetrics{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+				Usage: v1.ResourceList{
+					v1.ResourceCPU: *resource.NewMilliQuantity(100, resource.DecimalSI),
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+				Usage: v1.ResourceList{
+					v1.ResourceCPU: *resource.NewMilliQuantity(200, resource.DecimalSI),
+				},
+			},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		nodeName    string
+		found       bool
+		expectedCPU int64
+	}{
+		{"node1", "node1", true, 100},
+		{"node2", "node2", true, 200},
+		{"no metrics sample", "node3", false, 0},
+	}
+
+	index := GetNodeMetricsByName(metrics)
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			m, ok := index[test.nodeName]
+			if ok != test.found {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.found, ok)
+				return
+			}
+			if ok && m.Usage.Cpu().MilliValue() != test.expectedCPU {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expectedCPU, m.Usage.Cpu().MilliValue())
+				return
+			}
+		})
+	}
+}
+
+func TestContainerMetricsIndex(t *testing.T) {
+
+	// pod1 in awesome-ns has same pod and container name as pod1 in default
+	metrics := testMetrics.DeepCopy()
+	metrics.Items = append(metrics.Items, metricsapiv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "awesome-ns",
+		},
+		Containers: []metricsapiv1beta1.ContainerMetrics{
+			{
+				Name: "container1",
+				Usage: v1.ResourceList{
+					v1.ResourceCPU:    *resource.NewMilliQuantity(20, resource.DecimalSI),
+					v1.ResourceMemory: *resource.NewQuantity(30, resource.DecimalSI),
+				},
+			},
+		},
+	})
 
 	var tests = []struct {
 		description   string
+		namespace     string
 		podName       string
 		containerName string
 		expectedCPU   int64
 		expectedMEM   int64
 	}{
-		{"10 and 10", "pod1", "container1", 10, 10},
-		{"0 and 0", "pod999", "container999", 0, 0},
+		{"10 and 10", "default", "pod1", "container1", 10, 10},
+		{"same pod name in other namespace", "awesome-ns", "pod1", "container1", 20, 30},
+		{"no pod in namespace", "kube-system", "pod1", "container1", 0, 0},
+		{"0 and 0", "default", "pod999", "container999", 0, 0},
 	}
 
+	index := NewContainerMetricsIndex(metrics)
+
 	for _, test := range tests {
-		t.Run("[GetContainerMetrics] cpu and mem", func(t *testing.T) {
-			actualCPU, actualMEM := GetContainerMetrics(testMetrics, test.podName, test.containerName)
+		t.Run(test.description, func(t *testing.T) {
+			actualCPU, actualMEM := index.Get(test.namespace, test.podName, test.containerName)
 			if actualCPU != test.expectedCPU {
 				t.Errorf("[%s cpu] expected(%d) differ (got: %d)", test.description, test.expectedCPU, actualCPU)
 				return
@@ -602,6 +1067,106 @@ func TestGetContainerMetrics(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("nil metrics", func(t *testing.T) {
+		actualCPU, actualMEM := NewContainerMetricsIndex(nil).Get("default", "pod1", "container1")
+		if actualCPU != 0 || actualMEM != 0 {
+			t.Errorf("[nil metrics] expected(0, 0) differ (got: %d, %d)", actualCPU, actualMEM)
+			return
+		}
+	})
+}
+
+func TestIsCountedPhase(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		phase       string
+		phases      []string
+		expected    bool
+	}{
+		{"running by default", "Running", []string{}, true},
+		{"pending by default", "Pending", []string{}, true},
+		{"unknown by default", "Unknown", nil, true},
+		{"succeeded by default", "Succeeded", []string{}, false},
+		{"failed by default", "Failed", []string{}, false},
+		{"pending with running only", "Pending", []string{"Running"}, false},
+		{"failed with failed", "Failed", []string{"Running", "Failed"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := IsCountedPhase(test.phase, test.phases)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%t) differ (got: %t)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestFilterPods(t *testing.T) {
+
+	pending := testPods[1].DeepCopy()
+	pending.Status.Phase = v1.PodPending
+
+	pods := v1.PodList{
+		Items: []v1.Pod{
+			testPods[0], // Running
+			*pending,    // Pending
+			testPods[2], // Failed
+		},
+	}
+
+	var tests = []struct {
+		description string
+		phases      []string
+		expected    []string
+	}{
+		{"default", DefaultCountPhases, []string{"pod1", "pod2"}},
+		{"running only", []string{"Running"}, []string{"pod1"}},
+		{"failed only", []string{"Failed"}, []string{"pod3"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := []string{}
+			for _, pod := range FilterPods(pods, test.phases).Items {
+				actual = append(actual, pod.ObjectMeta.Name)
+			}
+			if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
+}
+
+func TestGetTerminatingPodCount(t *testing.T) {
+
+	now := metav1.Now()
+	terminating := testPods[1].DeepCopy()
+	terminating.ObjectMeta.DeletionTimestamp = &now
+
+	var tests = []struct {
+		description string
+		pods        v1.PodList
+		expected    int
+	}{
+		{"1 terminating pod", v1.PodList{Items: []v1.Pod{testPods[0], *terminating}}, 1},
+		{"no terminating pods", v1.PodList{Items: []v1.Pod{testPods[0], testPods[1]}}, 0},
+		{"0 pods", v1.PodList{Items: []v1.Pod{}}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetTerminatingPodCount(test.pods)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expected, actual)
+				return
+			}
+		})
+	}
 }
 
 func TestGetPodCount(t *testing.T) {
@@ -752,4 +1317,29 @@ func TestColor(t *testing.T) {
 			return
 		}
 	})
+
+	t.Run("highlight", func(t *testing.T) {
+		s := "foo"
+		expected := []byte(color.OpReverse.Render(s))
+		Highlight(&s)
+		actual := []byte(s)
+		if !bytes.Equal(actual, expected) {
+			t.Errorf(
+				"[highlight] expected(%v) differ (got: %v)",
+				expected,
+				actual,
+			)
+			return
+		}
+	})
+
+	t.Run("no highlight", func(t *testing.T) {
+		h, n := "foo", "foo"
+		Highlight(&h)
+		NoHighlight(&n)
+		if len(h) != len(n) {
+			t.Errorf("[no highlight] expected length(%d) differ (got: %d)", len(h), len(n))
+			return
+		}
+	})
 }
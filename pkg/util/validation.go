@@ -2,6 +2,10 @@ package util
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
 )
 
 func ValidateThreshold(w, c int64) error {
@@ -13,3 +17,95 @@ func ValidateThreshold(w, c int64) error {
 
 	return nil
 }
+
+// ValidateOutputFormat ensures that output format is supported
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case "", "json", "yaml", "csv", "tsv":
+		return nil
+	}
+
+	// template formats need an argument like "jsonpath={.items[*].name}"
+	templateFormats := []string{
+		"custom-columns=",
+		"custom-columns-file=",
+		"go-template=",
+		"go-template-file=",
+		"template=",
+		"templatefile=",
+		"jsonpath=",
+		"jsonpath-file=",
+	}
+
+	for _, f := range templateFormats {
+		if strings.HasPrefix(format, f) && len(format) > len(f) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(
+		"unsupported output format: %s (allowed formats: json, yaml, csv, tsv, %s...)",
+		format,
+		strings.Join(templateFormats, "..., "),
+	)
+}
+
+// ValidateCountPhases ensures that pod phases are valid
+func ValidateCountPhases(phases []string) error {
+	allowed := []string{
+		string(v1.PodPending),
+		string(v1.PodRunning),
+		string(v1.PodSucceeded),
+		string(v1.PodFailed),
+		string(v1.PodUnknown),
+	}
+
+	for _, phase := range phases {
+		valid := false
+		for _, a := range allowed {
+			if phase == a {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf(
+				"unsupported pod phase: %s (allowed phases: %s)",
+				phase,
+				strings.Join(allowed, ", "),
+			)
+		}
+	}
+
+	return nil
+}
+
+// ValidateResources ensures that extended resources do not have dedicated columns
+func ValidateResources(names []string) error {
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("resource name must not be empty")
+		}
+		if IsStandardResource(v1.ResourceName(name)) {
+			return fmt.Errorf(
+				"unsupported resource: %s (cpu, memory, pods and ephemeral-storage have dedicated columns)",
+				name,
+			)
+		}
+	}
+
+	return nil
+}
+
+// ValidateWatch ensures that watch mode is used with table output and positive interval
+func ValidateWatch(output string, interval time.Duration) error {
+	if output != "" {
+		return fmt.Errorf("--watch is supported only with table output (got: %s)", output)
+	}
+
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be greater than 0 (got: %s)", interval)
+	}
+
+	return nil
+}
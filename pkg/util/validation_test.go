@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestValidateThreshold(t *testing.T) {
@@ -29,3 +30,107 @@ func TestValidateThreshold(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateOutputFormat(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		format      string
+		expected    error
+	}{
+		{"empty", "", nil},
+		{"json", "json", nil},
+		{"yaml", "yaml", nil},
+		{"csv", "csv", nil},
+		{"tsv", "tsv", nil},
+		{"custom-columns", "custom-columns=NAME:.name", nil},
+		{"jsonpath", "jsonpath={.items[*].name}", nil},
+		{"go-template without template", "go-template=", fmt.Errorf("unsupported output format: go-template= (allowed formats: json, yaml, csv, tsv, custom-columns=..., custom-columns-file=..., go-template=..., go-template-file=..., template=..., templatefile=..., jsonpath=..., jsonpath-file=...)")},
+		{"wide", "wide", fmt.Errorf("unsupported output format: wide (allowed formats: json, yaml, csv, tsv, custom-columns=..., custom-columns-file=..., go-template=..., go-template-file=..., template=..., templatefile=..., jsonpath=..., jsonpath-file=...)")},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.description, func(t *testing.T) {
+			actual := ValidateOutputFormat(test.format)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected(%v) differ (got: %v)", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateCountPhases(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		phases      []string
+		expected    error
+	}{
+		{"empty", []string{}, nil},
+		{"default", DefaultCountPhases, nil},
+		{"running and pending", []string{"Running", "Pending"}, nil},
+		{"terminal phases", []string{"Succeeded", "Failed"}, nil},
+		{"lower case", []string{"running"}, fmt.Errorf("unsupported pod phase: running (allowed phases: Pending, Running, Succeeded, Failed, Unknown)")},
+		{"terminating", []string{"Running", "Terminating"}, fmt.Errorf("unsupported pod phase: Terminating (allowed phases: Pending, Running, Succeeded, Failed, Unknown)")},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.description, func(t *testing.T) {
+			actual := ValidateCountPhases(test.phases)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected(%v) differ (got: %v)", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		resources   []string
+		expected    error
+	}{
+		{"empty", []string{}, nil},
+		{"extended resources", []string{"nvidia.com/gpu", "hugepages-2Mi"}, nil},
+		{"empty name", []string{""}, fmt.Errorf("resource name must not be empty")},
+		{"cpu", []string{"nvidia.com/gpu", "cpu"}, fmt.Errorf("unsupported resource: cpu (cpu, memory, pods and ephemeral-storage have dedicated columns)")},
+		{"ephemeral-storage", []string{"ephemeral-storage"}, fmt.Errorf("unsupported resource: ephemeral-storage (cpu, memory, pods and ephemeral-storage have dedicated columns)")},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.description, func(t *testing.T) {
+			actual := ValidateResources(test.resources)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected(%v) differ (got: %v)", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateWatch(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		output      string
+		interval    time.Duration
+		expected    error
+	}{
+		{"table", "", 5 * time.Second, nil},
+		{"json", "json", 5 * time.Second, fmt.Errorf("--watch is supported only with table output (got: json)")},
+		{"zero interval", "", 0, fmt.Errorf("--interval must be greater than 0 (got: 0s)")},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.description, func(t *testing.T) {
+			actual := ValidateWatch(test.output, test.interval)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected(%v) differ (got: %v)", test.expected, actual)
+			}
+		})
+	}
+}